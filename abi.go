@@ -0,0 +1,598 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// abiArgument is the JSON ABI form of one parameter
+type abiArgument struct {
+	Name       string        `json:"name"`
+	Type       string        `json:"type"`
+	Indexed    bool          `json:"indexed,omitempty"`
+	Components []abiArgument `json:"components,omitempty"`
+}
+
+type abiEntry struct {
+	Type    string        `json:"type"`
+	Name    string        `json:"name"`
+	Inputs  []abiArgument `json:"inputs"`
+	Outputs []abiArgument `json:"outputs,omitempty"`
+}
+
+type abiParser struct {
+	s   string
+	pos int
+}
+
+// ParseABISignature turns a human readable signature such as
+// "function transfer(address to, uint256 amount) returns (bool)" or
+// "event Transfer(address indexed from, address indexed to, uint256 value)" into an ABI
+func ParseABISignature(signature, defaultType string) (abi.ABI, error) {
+	p := &abiParser{s: strings.TrimSpace(signature)}
+	entry := abiEntry{Type: defaultType}
+	if word := p.peekIdent(); word == "function" || word == "event" || word == "error" {
+		entry.Type = p.ident()
+	}
+	entry.Name = p.ident()
+	if entry.Name == "" {
+		return abi.ABI{}, p.errorf("want a function or event name")
+	}
+	var err error
+	if entry.Inputs, err = p.params(); err != nil {
+		return abi.ABI{}, err
+	}
+	for p.skipSpace(); p.pos < len(p.s); p.skipSpace() {
+		pos := p.pos
+		switch word := p.ident(); word {
+		case "returns":
+			if entry.Outputs, err = p.params(); err != nil {
+				return abi.ABI{}, err
+			}
+		case "external", "public", "internal", "private", "view", "pure", "payable", "nonpayable", "constant":
+		default:
+			if p.pos = pos; word == "" {
+				return abi.ABI{}, p.errorf("unexpected %q", p.s[p.pos:])
+			}
+			return abi.ABI{}, p.errorf("unexpected %q", word)
+		}
+	}
+	bytez, _ := json.Marshal([]abiEntry{entry})
+	return abi.JSON(bytes.NewReader(bytez))
+}
+
+func (p *abiParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("signature offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *abiParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *abiParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *abiParser) peekIdent() string {
+	pos := p.pos
+	word := p.ident()
+	p.pos = pos
+	return word
+}
+
+func (p *abiParser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !(c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *abiParser) params() ([]abiArgument, error) {
+	if p.peek() != '(' {
+		return nil, p.errorf("want '('")
+	}
+	p.pos++
+	args := []abiArgument{}
+	if p.peek() == ')' {
+		p.pos++
+		return args, nil
+	}
+	for {
+		arg, err := p.param()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return args, nil
+		default:
+			return nil, p.errorf("want ',' or ')'")
+		}
+	}
+}
+
+func (p *abiParser) param() (arg abiArgument, err error) {
+	if p.peek() == '(' || p.peekIdent() == "tuple" {
+		p.ident()
+		arg.Type = "tuple"
+		if arg.Components, err = p.params(); err != nil {
+			return arg, err
+		}
+		//abi.JSON turns components into struct fields, which need a name
+		for i := range arg.Components {
+			if strings.Trim(arg.Components[i].Name, "_") == "" {
+				arg.Components[i].Name = fmt.Sprintf("field%d", i)
+			}
+		}
+	} else if arg.Type = p.ident(); arg.Type == "" {
+		return arg, p.errorf("want a type")
+	}
+	for p.peek() == '[' {
+		end := strings.IndexByte(p.s[p.pos:], ']')
+		if end < 0 {
+			return arg, p.errorf("unclosed '['")
+		}
+		arg.Type += p.s[p.pos : p.pos+end+1]
+		p.pos += end + 1
+	}
+	for word := p.peekIdent(); word != ""; word = p.peekIdent() {
+		p.ident()
+		switch word {
+		case "indexed":
+			arg.Indexed = true
+		case "memory", "calldata", "storage", "payable":
+		default:
+			arg.Name = word
+		}
+	}
+	return arg, nil
+}
+
+// LoadABI reads either a JSON ABI fragment or a human readable signature
+func LoadABI(ab *AbiBody, defaultType string) (abi.ABI, error) {
+	if len(ab.Abi) != 0 {
+		fragment := bytes.TrimSpace(ab.Abi)
+		if len(fragment) > 0 && fragment[0] == '{' {
+			fragment = append(append([]byte{'['}, fragment...), ']')
+		}
+		return abi.JSON(bytes.NewReader(fragment))
+	}
+	if ab.Signature == "" {
+		return abi.ABI{}, errors.New("want signature or abi")
+	}
+	return ParseABISignature(ab.Signature, defaultType)
+}
+
+func findMethod(contract abi.ABI, name string) (*abi.Method, error) {
+	if name != "" {
+		method, ok := contract.Methods[name]
+		if !ok {
+			return nil, fmt.Errorf("no function %s in abi", name)
+		}
+		return &method, nil
+	}
+	if len(contract.Methods) != 1 {
+		return nil, fmt.Errorf("abi has %d functions, set name", len(contract.Methods))
+	}
+	for _, method := range contract.Methods {
+		return &method, nil
+	}
+	return nil, nil
+}
+
+func findEvent(contract abi.ABI, name string) (*abi.Event, error) {
+	if name != "" {
+		event, ok := contract.Events[name]
+		if !ok {
+			return nil, fmt.Errorf("no event %s in abi", name)
+		}
+		return &event, nil
+	}
+	if len(contract.Events) != 1 {
+		return nil, fmt.Errorf("abi has %d events, set name", len(contract.Events))
+	}
+	for _, event := range contract.Events {
+		return &event, nil
+	}
+	return nil, nil
+}
+
+type ABIValue struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+func AbiContent(ab *AbiBody) (interface{}, error) {
+	defaultType := "function"
+	if ab.Operation == "topic" || ab.Operation == "decode_log" {
+		defaultType = "event"
+	}
+	contract, err := LoadABI(ab, defaultType)
+	if err != nil {
+		return nil, err
+	}
+	switch ab.Operation {
+	case "selector":
+		method, err := findMethod(contract, ab.Name)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Signature string `json:"signature"`
+			Selector  string `json:"selector"`
+		}
+		result.Signature = method.Sig
+		result.Selector = hexutil.Encode(method.ID)
+		return result, nil
+	case "topic":
+		event, err := findEvent(contract, ab.Name)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Signature string `json:"signature"`
+			Topic     string `json:"topic"`
+		}
+		result.Signature = event.Sig
+		result.Topic = event.ID.Hex()
+		return result, nil
+	case "encode":
+		method, err := findMethod(contract, ab.Name)
+		if err != nil {
+			return nil, err
+		}
+		args, err := abiArgs(method.Inputs, ab.Args)
+		if err != nil {
+			return nil, err
+		}
+		packed, err := method.Inputs.Pack(args...)
+		if err != nil {
+			return nil, err
+		}
+		return hexutil.Encode(append(append([]byte{}, method.ID...), packed...)), nil
+	case "decode":
+		data, err := hexutil.Decode(withHexPrefix(ab.Data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid calldata: %v", err)
+		}
+		if len(data) < 4 {
+			return nil, fmt.Errorf("calldata too short: %d bytes, want a 4-byte selector", len(data))
+		}
+		method, err := contract.MethodById(data[:4])
+		if err != nil {
+			return nil, err
+		}
+		values, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Signature string     `json:"signature"`
+			Args      []ABIValue `json:"args"`
+		}
+		result.Signature = method.Sig
+		result.Args = abiValues(method.Inputs, values)
+		return result, nil
+	case "decode_output":
+		method, err := findMethod(contract, ab.Name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := hexutil.Decode(withHexPrefix(ab.Data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid return data: %v", err)
+		}
+		values, err := method.Outputs.Unpack(data)
+		if err != nil {
+			return nil, err
+		}
+		return abiValues(method.Outputs, values), nil
+	case "decode_log":
+		return decodeLog(contract, ab)
+	}
+	return nil, fmt.Errorf("invalid abi operation: %s", ab.Operation)
+}
+
+func decodeLog(contract abi.ABI, ab *AbiBody) (interface{}, error) {
+	topics := make([]common.Hash, len(ab.Topics))
+	for i, topic := range ab.Topics {
+		b, err := hexutil.Decode(withHexPrefix(topic))
+		if err != nil || len(b) != common.HashLength {
+			return nil, fmt.Errorf("topic %d: want 32 bytes hex", i)
+		}
+		topics[i] = common.BytesToHash(b)
+	}
+	var event *abi.Event
+	var err error
+	if ab.Name == "" && len(topics) > 0 {
+		event, err = contract.EventByID(topics[0])
+	} else {
+		event, err = findEvent(contract, ab.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !event.Anonymous {
+		if len(topics) == 0 || topics[0] != event.ID {
+			return nil, fmt.Errorf("topic 0 is not %s (%s)", event.Sig, event.ID.Hex())
+		}
+		topics = topics[1:]
+	}
+	data, err := hexutil.Decode(withHexPrefix(ab.Data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid log data: %v", err)
+	}
+	nonIndexed, err := event.Inputs.NonIndexed().Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	var values []ABIValue
+	for _, input := range event.Inputs {
+		value := ABIValue{Name: input.Name, Type: input.Type.String()}
+		if !input.Indexed {
+			value.Value = abiJSON(reflect.ValueOf(nonIndexed[0]))
+			nonIndexed = nonIndexed[1:]
+			values = append(values, value)
+			continue
+		}
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("missing topic for indexed %s", input.Name)
+		}
+		topic := topics[0]
+		topics = topics[1:]
+		switch input.Type.T {
+		case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+			//dynamic indexed values are only kept as their keccak256 hash
+			value.Value = topic.Hex()
+		default:
+			decoded, err := abi.Arguments{{Type: input.Type}}.Unpack(topic[:])
+			if err != nil {
+				return nil, fmt.Errorf("topic of %s: %v", input.Name, err)
+			}
+			value.Value = abiJSON(reflect.ValueOf(decoded[0]))
+		}
+		values = append(values, value)
+	}
+	if len(topics) != 0 {
+		return nil, fmt.Errorf("%d topics left over", len(topics))
+	}
+	var result struct {
+		Signature string     `json:"signature"`
+		Args      []ABIValue `json:"args"`
+	}
+	result.Signature = event.Sig
+	result.Args = values
+	return result, nil
+}
+
+func abiValues(arguments abi.Arguments, values []interface{}) []ABIValue {
+	result := make([]ABIValue, len(values))
+	for i, value := range values {
+		result[i] = ABIValue{Name: arguments[i].Name, Type: arguments[i].Type.String(), Value: abiJSON(reflect.ValueOf(value))}
+	}
+	return result
+}
+
+// abiJSON renders unpacked values as JSON: integers as decimal strings, bytes and addresses as hex
+func abiJSON(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	switch value := v.Interface().(type) {
+	case *big.Int:
+		return value.String()
+	case common.Address:
+		return value.Hex()
+	case common.Hash:
+		return value.Hex()
+	case []byte:
+		return hexutil.Encode(value)
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(v.Uint())
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = abiJSON(v.Index(i))
+		}
+		return items
+	case reflect.Struct:
+		fields := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = v.Type().Field(i).Name
+			}
+			fields[name] = abiJSON(v.Field(i))
+		}
+		return fields
+	case reflect.Ptr:
+		return abiJSON(v.Elem())
+	}
+	return v.Interface()
+}
+
+func abiArgs(arguments abi.Arguments, raw json.RawMessage) ([]interface{}, error) {
+	var items []json.RawMessage
+	if len(raw) != 0 {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("args must be a JSON array: %v", err)
+		}
+	}
+	if len(items) != len(arguments) {
+		return nil, fmt.Errorf("got %d args, want %d", len(items), len(arguments))
+	}
+	args := make([]interface{}, len(items))
+	for i, item := range items {
+		value, err := abiGoValue(arguments[i].Type, item)
+		if err != nil {
+			return nil, fmt.Errorf("arg %d (%s): %v", i, arguments[i].Type.String(), err)
+		}
+		args[i] = value.Interface()
+	}
+	return args, nil
+}
+
+// abiGoValue converts a JSON argument into the Go type go-ethereum packs for t
+func abiGoValue(t abi.Type, raw json.RawMessage) (reflect.Value, error) {
+	goType := t.GetType()
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		var s json.Number
+		if err := json.Unmarshal(raw, &s); err != nil {
+			var str string
+			if err = json.Unmarshal(raw, &str); err != nil {
+				return reflect.Value{}, errors.New("want an integer or a decimal/0x string")
+			}
+			s = json.Number(str)
+		}
+		n, ok := new(big.Int).SetString(string(s), 0)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("invalid integer %s", s)
+		}
+		if goType == reflect.TypeOf(&big.Int{}) {
+			min, max := new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(t.Size))
+			if t.T == abi.IntTy {
+				max.Rsh(max, 1)
+				min.Neg(max)
+			}
+			if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+				return reflect.Value{}, fmt.Errorf("%s overflows %s", n, t.String())
+			}
+			return reflect.ValueOf(n), nil
+		}
+		value := reflect.New(goType).Elem()
+		if t.T == abi.IntTy {
+			if !n.IsInt64() || value.OverflowInt(n.Int64()) {
+				return reflect.Value{}, fmt.Errorf("%s overflows %s", n, goType)
+			}
+			value.SetInt(n.Int64())
+		} else {
+			if n.Sign() < 0 || !n.IsUint64() || value.OverflowUint(n.Uint64()) {
+				return reflect.Value{}, fmt.Errorf("%s overflows %s", n, goType)
+			}
+			value.SetUint(n.Uint64())
+		}
+		return value, nil
+	case abi.BoolTy:
+		var b bool
+		err := json.Unmarshal(raw, &b)
+		return reflect.ValueOf(b), err
+	case abi.StringTy:
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return reflect.ValueOf(s), err
+	case abi.AddressTy:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil || !common.IsHexAddress(s) {
+			return reflect.Value{}, errors.New("want a 20-byte hex address")
+		}
+		return reflect.ValueOf(common.HexToAddress(s)), nil
+	case abi.BytesTy, abi.FixedBytesTy, abi.FunctionTy:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return reflect.Value{}, errors.New("want a hex string")
+		}
+		b, err := hexutil.Decode(withHexPrefix(s))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if t.T == abi.BytesTy {
+			return reflect.ValueOf(b), nil
+		}
+		value := reflect.New(goType).Elem()
+		if len(b) != value.Len() {
+			return reflect.Value{}, fmt.Errorf("got %d bytes, want %d", len(b), value.Len())
+		}
+		reflect.Copy(value, reflect.ValueOf(b))
+		return value, nil
+	case abi.SliceTy, abi.ArrayTy:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return reflect.Value{}, errors.New("want an array")
+		}
+		var value reflect.Value
+		if t.T == abi.SliceTy {
+			value = reflect.MakeSlice(goType, len(items), len(items))
+		} else {
+			if len(items) != t.Size {
+				return reflect.Value{}, fmt.Errorf("got %d items, want %d", len(items), t.Size)
+			}
+			value = reflect.New(goType).Elem()
+		}
+		for i, item := range items {
+			elem, err := abiGoValue(*t.Elem, item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("[%d]: %v", i, err)
+			}
+			value.Index(i).Set(elem)
+		}
+		return value, nil
+	case abi.TupleTy:
+		//a tuple is either a positional array or an object keyed by component name
+		fields := make([]json.RawMessage, len(t.TupleElems))
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err == nil {
+			if len(items) != len(fields) {
+				return reflect.Value{}, fmt.Errorf("got %d tuple items, want %d", len(items), len(fields))
+			}
+			copy(fields, items)
+		} else {
+			var named map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &named); err != nil {
+				return reflect.Value{}, errors.New("want an array or an object")
+			}
+			for i, name := range t.TupleRawNames {
+				if fields[i] = named[name]; fields[i] == nil {
+					return reflect.Value{}, fmt.Errorf("missing tuple field %s", name)
+				}
+			}
+		}
+		value := reflect.New(goType).Elem()
+		for i, field := range fields {
+			elem, err := abiGoValue(*t.TupleElems[i], field)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf(".%s: %v", t.TupleRawNames[i], err)
+			}
+			value.Field(i).Set(elem)
+		}
+		return value, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported abi type %s", t.String())
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseABISignature(t *testing.T) {
+	tests := []struct {
+		signature string
+		sig       string
+		names     []string
+	}{
+		{"f((uint256,address))", "f((uint256,address))", []string{""}},
+		{"f((uint256,address)[])", "f((uint256,address)[])", []string{""}},
+		{"f(tuple(uint256 _, address)[2] pair)", "f((uint256,address)[2])", []string{"pair"}},
+		{"function submit((uint256 amount, address to) order, bytes calldata data)", "submit((uint256,address),bytes)", []string{"order", "data"}},
+		{"f((uint256,(address,bytes32)[]) x, string memory s)", "f((uint256,(address,bytes32)[]),string)", []string{"x", "s"}},
+		{"transfer(address to, uint256 amount) returns (bool)", "transfer(address,uint256)", []string{"to", "amount"}},
+	}
+	for _, test := range tests {
+		contract, err := ParseABISignature(test.signature, "function")
+		if !assert.Nil(t, err, test.signature) {
+			continue
+		}
+		method, err := findMethod(contract, "")
+		assert.Nil(t, err)
+		assert.Equal(t, test.sig, method.Sig)
+		assert.Equal(t, crypto.Keccak256([]byte(test.sig))[:4], method.ID)
+		var names []string
+		for _, input := range method.Inputs {
+			names = append(names, input.Name)
+		}
+		assert.Equal(t, test.names, names, test.signature)
+	}
+
+	contract, err := ParseABISignature("f((uint256,address _,(bool,bytes32 tag)) t)", "function")
+	assert.Nil(t, err)
+	tuple := contract.Methods["f"].Inputs[0].Type
+	assert.Equal(t, []string{"field0", "field1", "field2"}, tuple.TupleRawNames)
+	assert.Equal(t, []string{"field0", "tag"}, tuple.TupleElems[2].TupleRawNames)
+
+	for _, invalid := range []string{"f(uint256", "f(uint256[)", "(uint256)", "f(uint256,)", "f() returns", "f(uint256) extra("} {
+		_, err := ParseABISignature(invalid, "function")
+		assert.NotNil(t, err, invalid)
+	}
+
+	_, err = ParseABISignature("f(uint256) external view garbage", "function")
+	assert.EqualError(t, err, `signature offset 25: unexpected "garbage"`)
+	_, err = ParseABISignature("function f(uint256) public payable returns (bool) override", "function")
+	assert.EqualError(t, err, `signature offset 50: unexpected "override"`)
+	_, err = ParseABISignature("f(uint256) pure nonpayable constant private internal", "function")
+	assert.Nil(t, err)
+}
+
+func TestParseABISignatureReturnsAndIndexed(t *testing.T) {
+	contract, err := ParseABISignature("function balanceOf(address owner) external view returns (uint256 balance)", "function")
+	assert.Nil(t, err)
+	method := contract.Methods["balanceOf"]
+	assert.Equal(t, "0x70a08231", hexutil.Encode(method.ID))
+	assert.Equal(t, 1, len(method.Outputs))
+	assert.Equal(t, "balance", method.Outputs[0].Name)
+
+	contract, err = ParseABISignature("function get() returns ((uint256,address)[], bool)", "function")
+	assert.Nil(t, err)
+	outputs := contract.Methods["get"].Outputs
+	assert.Equal(t, "(uint256,address)[]", outputs[0].Type.String())
+	assert.Equal(t, []string{"field0", "field1"}, outputs[0].Type.Elem.TupleRawNames)
+
+	contract, err = ParseABISignature("event Transfer(address indexed from, address indexed to, uint256 value)", "function")
+	assert.Nil(t, err)
+	event := contract.Events["Transfer"]
+	assert.Equal(t, "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", event.ID.Hex())
+	var indexed []bool
+	for _, input := range event.Inputs {
+		indexed = append(indexed, input.Indexed)
+	}
+	assert.Equal(t, []bool{true, true, false}, indexed)
+}
+
+func TestAbiAnonymousTupleRoundTrip(t *testing.T) {
+	signature := "f((uint256,address)[])"
+	args := `[[[1, "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"], {"field0": "0x02", "field1": "0x0000000000000000000000000000000000000001"}]]`
+	calldata, err := AbiContent(&AbiBody{Operation: "encode", Signature: signature, Args: json.RawMessage(args)})
+	assert.Nil(t, err)
+
+	decoded, err := AbiContent(&AbiBody{Operation: "decode", Signature: signature, Data: calldata.(string)})
+	assert.Nil(t, err)
+	bytez, _ := json.Marshal(decoded)
+	assert.JSONEq(t, `{"signature": "f((uint256,address)[])", "args": [{"name": "", "type": "(uint256,address)[]", "value": [
+		{"field0": "1", "field1": "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"},
+		{"field0": "2", "field1": "0x0000000000000000000000000000000000000001"}]}]}`, string(bytez))
+}
+
+func TestAbiBigIntRange(t *testing.T) {
+	tests := []struct {
+		signature string
+		arg       string
+		err       string
+	}{
+		// 2^256
+		{"f(uint256)", `"0x1` + strings.Repeat("0", 64) + `"`, "arg 0 (uint256): " + new(big.Int).Lsh(big.NewInt(1), 256).String() + " overflows uint256"},
+		// 2^88
+		{"f(uint72)", `"0x1` + strings.Repeat("0", 22) + `"`, "arg 0 (uint72): 309485009821345068724781056 overflows uint72"},
+		{"f(uint72)", `-1`, "arg 0 (uint72): -1 overflows uint72"},
+		// -2^144
+		{"f(int128)", `"-0x1` + strings.Repeat("0", 36) + `"`, "arg 0 (int128): -22300745198530623141535718272648361505980416 overflows int128"},
+		// 2^127
+		{"f(int128)", `"0x8` + strings.Repeat("0", 31) + `"`, "arg 0 (int128): 170141183460469231731687303715884105728 overflows int128"},
+	}
+	for _, test := range tests {
+		_, err := AbiContent(&AbiBody{Operation: "encode", Signature: test.signature, Args: json.RawMessage("[" + test.arg + "]")})
+		assert.EqualError(t, err, test.err, test.signature)
+	}
+
+	// the bounds themselves still encode
+	for signature, arg := range map[string]string{
+		"f(uint256)": `"0x` + strings.Repeat("f", 64) + `"`,
+		"f(uint72)":  `"0x` + strings.Repeat("f", 18) + `"`,
+		"f(int128)":  `"-0x8` + strings.Repeat("0", 31) + `"`,
+		"f(int136)":  `"0x7f` + strings.Repeat("f", 32) + `"`,
+	} {
+		_, err := AbiContent(&AbiBody{Operation: "encode", Signature: signature, Args: json.RawMessage("[" + arg + "]")})
+		assert.Nil(t, err, signature)
+	}
+}
@@ -1,63 +1,172 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
-func BinToNum(input string) (num int) {
-	l := len(input)
-	for i := l - 1; i >= 0; i-- {
-		num += (int(input[l-i-1]) - 48) << uint8(i)
-	}
-	return
+// RadixOptions describe how text maps to digit groups:
+// mode utf8 turns every UTF-8 byte into a group, mode unicode turns every code point
+// (or UTF-16 code unit when bits is 16) into a group of bits wide
+type RadixOptions struct {
+	Radix     int
+	Bits      int
+	Mode      string
+	Separator string
 }
 
-func NumToBin(input int) (bin string) {
-	if input == 0 {
-		return "00000000"
+func (o *RadixOptions) normalize() error {
+	if o.Radix == 0 {
+		o.Radix = 2
 	}
-	bytez := []byte{48, 48, 48, 48, 48, 48, 48, 48}
-	i := len(bytez) - 1
-	for ; input > 0; input /= 2 {
-		bytez[i] = byte(48 + input%2)
-		i--
+	if o.Bits == 0 {
+		o.Bits = 8
 	}
-	bin = string(bytez)
-	return
+	if o.Mode == "" {
+		o.Mode = "utf8"
+	}
+	switch o.Radix {
+	case 2, 8, 10, 16:
+	default:
+		return fmt.Errorf("invalid radix: %d, want 2, 8, 10 or 16", o.Radix)
+	}
+	switch o.Bits {
+	case 7, 8, 16, 32:
+	default:
+		return fmt.Errorf("invalid bits: %d, want 7, 8, 16 or 32", o.Bits)
+	}
+	if o.Mode != "utf8" && o.Mode != "unicode" {
+		return fmt.Errorf("invalid mode: %s, want utf8 or unicode", o.Mode)
+	}
+	return nil
 }
 
-func main() {
-	str := `
-package main
+// digits is the fixed width of one group, so groups can be joined without a separator
+func (o *RadixOptions) digits() int {
+	return int(math.Ceil(float64(o.Bits) / math.Log2(float64(o.Radix))))
+}
+
+func (o *RadixOptions) units(data []byte) ([]uint32, error) {
+	var units []uint32
+	switch {
+	case o.Mode == "utf8":
+		for _, b := range data {
+			units = append(units, uint32(b))
+		}
+	case o.Bits == 16:
+		if !utf8.Valid(data) {
+			return nil, errors.New("unicode mode needs valid UTF-8 input")
+		}
+		for _, u := range utf16.Encode([]rune(string(data))) {
+			units = append(units, uint32(u))
+		}
+	default:
+		if !utf8.Valid(data) {
+			return nil, errors.New("unicode mode needs valid UTF-8 input")
+		}
+		for _, r := range string(data) {
+			units = append(units, uint32(r))
+		}
+	}
+	for i, u := range units {
+		if o.Bits < 32 && u >= 1<<uint(o.Bits) {
+			return nil, fmt.Errorf("unit %d (0x%x) does not fit in %d bits", i, u, o.Bits)
+		}
+	}
+	return units, nil
+}
 
-import "fmt"
+func (o *RadixOptions) text(units []uint32) ([]byte, error) {
+	switch {
+	case o.Mode == "utf8":
+		data := make([]byte, len(units))
+		for i, u := range units {
+			if u > 0xff {
+				return nil, fmt.Errorf("group %d (%d) is not a byte", i, u)
+			}
+			data[i] = byte(u)
+		}
+		return data, nil
+	case o.Bits == 16:
+		u16 := make([]uint16, len(units))
+		for i, u := range units {
+			u16[i] = uint16(u)
+		}
+		return []byte(string(utf16.Decode(u16))), nil
+	}
+	var sb strings.Builder
+	for i, u := range units {
+		if !utf8.ValidRune(rune(u)) {
+			return nil, fmt.Errorf("group %d (0x%x) is not a unicode code point", i, u)
+		}
+		sb.WriteRune(rune(u))
+	}
+	return []byte(sb.String()), nil
+}
 
-//M6Ly9naXRodWIuY29tL2RhcHBsZWRnZXIvQW5uQ2hhaW4vaXNzdWVzLzM3aHR0cH
-func main() {
-	fmt.Println("Clockwise rotate 6")
+func EncodeRadix(data []byte, opts RadixOptions) (string, error) {
+	if err := opts.normalize(); err != nil {
+		return "", err
+	}
+	units, err := opts.units(data)
+	if err != nil {
+		return "", err
+	}
+	groups := make([]string, len(units))
+	for i, u := range units {
+		group := strconv.FormatUint(uint64(u), opts.Radix)
+		groups[i] = strings.Repeat("0", opts.digits()-len(group)) + group
+	}
+	return strings.Join(groups, opts.Separator), nil
 }
-`
-	result := Encrypt(str)
-	fmt.Println(result)
-	fmt.Println(Decrypt(result))
+
+func DecodeRadix(str string, opts RadixOptions) ([]byte, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	var groups []string
+	if opts.Separator != "" {
+		for _, group := range strings.Split(strings.TrimSpace(str), opts.Separator) {
+			if group = strings.TrimSpace(group); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	} else {
+		str = strings.Join(strings.Fields(str), "")
+		width := opts.digits()
+		if len(str)%width != 0 {
+			return nil, fmt.Errorf("invalid input: length %d is not a multiple of %d digits", len(str), width)
+		}
+		for i := 0; i < len(str); i += width {
+			groups = append(groups, str[i:i+width])
+		}
+	}
+	units := make([]uint32, len(groups))
+	for i, group := range groups {
+		u, err := strconv.ParseUint(group, opts.Radix, opts.Bits)
+		if err != nil {
+			return nil, fmt.Errorf("group %d %q: not a %d-bit base %d number", i, group, opts.Bits, opts.Radix)
+		}
+		units[i] = uint32(u)
+	}
+	return opts.text(units)
 }
 
+// Encrypt writes every UTF-8 byte of str as 8 binary digits
 func Encrypt(str string) (result string) {
-	for _, i := range str {
-		result += NumToBin(int(i))
-	}
+	result, _ = EncodeRadix([]byte(str), RadixOptions{})
 	return
 }
 
 func Decrypt(str string) (result string) {
-	str = "000010100111000001100001011000110110101101100001011001110110010100100000011011010110000101101001011011100000101000001010011010010110110101110000011011110111001001110100001000000010001001100110011011010111010000100010000010100000101000101111001011110100110100110110010011000111100100111001011011100110000101011000010100100110111101100100010101110100100101110101010110010011001000111001011101000100110000110010010100100110100001100011010010000100001001110011010110100101011101010010011011100101101001011000010010010111011001010001010101110011010101110101010100010011001001101000011010000110000101010111001101000111011001100001010110000100111001111010011001000101011101010110011110100100110001111010010011010011001101100001010010000101001000110000011000110100100000001010011001100111010101101110011000110010000001101101011000010110100101101110001010000010100100100000011110110000101000001001011001100110110101110100001011100101000001110010011010010110111001110100011011000110111000101000001000100100001101101100011011110110001101101011011101110110100101110011011001010010000001110010011011110111010001100001011101000110010100100000001101100010001000101001000010100111110100001010"
-	if len(str)%8 != 0 {
+	bytez, err := DecodeRadix(str, RadixOptions{})
+	if err != nil {
 		return "invalid input"
 	}
-	var bytez []byte
-	for i := 0; i < len(str)/8; i++ {
-		bytez = append(bytez, byte(BinToNum(str[i*8:(i+1)*8])))
-	}
-	result = string(bytez)
-	return
+	return string(bytez)
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRadixRoundTrip(t *testing.T) {
+	widths := map[int]map[int]int{
+		2:  {7: 7, 8: 8, 16: 16, 32: 32},
+		8:  {7: 3, 8: 3, 16: 6, 32: 11},
+		10: {7: 3, 8: 3, 16: 5, 32: 10},
+		16: {7: 2, 8: 2, 16: 4, 32: 8},
+	}
+	for radix, bitsWidths := range widths {
+		for bits, width := range bitsWidths {
+			for _, mode := range []string{"utf8", "unicode"} {
+				for _, sep := range []string{"", " ", ","} {
+					opts := RadixOptions{Radix: radix, Bits: bits, Mode: mode, Separator: sep}
+					name := fmt.Sprintf("%+v", opts)
+					encoded, err := EncodeRadix([]byte("Hi!"), opts)
+					if !assert.Nil(t, err, name) {
+						continue
+					}
+					groups := []string{encoded[:width], encoded[width : 2*width], encoded[2*width:]}
+					if sep != "" {
+						groups = strings.Split(encoded, sep)
+					}
+					assert.Equal(t, 3, len(groups), name)
+					for i, c := range "Hi!" {
+						assert.Equal(t, width, len(groups[i]), name)
+						digits := strconv.FormatInt(int64(c), radix)
+						assert.Equal(t, strings.Repeat("0", width-len(digits))+digits, groups[i], name)
+					}
+					decoded, err := DecodeRadix(encoded, opts)
+					assert.Nil(t, err, name)
+					assert.Equal(t, "Hi!", string(decoded), name)
+				}
+			}
+		}
+	}
+}
+
+func TestRadixVectors(t *testing.T) {
+	tests := []struct {
+		text    string
+		opts    RadixOptions
+		encoded string
+	}{
+		{"Hi", RadixOptions{Separator: " "}, "01001000 01101001"},
+		{"A", RadixOptions{Radix: 8, Bits: 7}, "101"},
+		{"é", RadixOptions{Radix: 16}, "c3a9"},
+		{"é", RadixOptions{Radix: 16, Mode: "unicode"}, "e9"},
+		{"€", RadixOptions{Radix: 16, Bits: 16, Mode: "unicode"}, "20ac"},
+		{"😀", RadixOptions{Radix: 16, Bits: 16, Mode: "unicode", Separator: " "}, "d83d de00"},
+		{"😀", RadixOptions{Radix: 10, Bits: 32, Mode: "unicode"}, "0000128512"},
+		{"😀", RadixOptions{Radix: 10, Separator: ","}, "240,159,152,128"},
+	}
+	for _, test := range tests {
+		encoded, err := EncodeRadix([]byte(test.text), test.opts)
+		assert.Nil(t, err)
+		assert.Equal(t, test.encoded, encoded, "%+v", test.opts)
+		decoded, err := DecodeRadix(test.encoded, test.opts)
+		assert.Nil(t, err)
+		assert.Equal(t, test.text, string(decoded), "%+v", test.opts)
+	}
+
+	// groups around the separator may be padded with spaces
+	decoded, err := DecodeRadix(" 72 , 105 ", RadixOptions{Radix: 10, Separator: ","})
+	assert.Nil(t, err)
+	assert.Equal(t, "Hi", string(decoded))
+
+	assert.Equal(t, "0100100001101001", Encrypt("Hi"))
+	assert.Equal(t, "Hi", Decrypt("01001000 01101001"))
+	assert.Equal(t, "invalid input", Decrypt("0100100"))
+}
+
+func TestRadixOverWide(t *testing.T) {
+	encodes := []struct {
+		text string
+		opts RadixOptions
+	}{
+		{"é", RadixOptions{Bits: 7}},
+		{"é", RadixOptions{Bits: 7, Mode: "unicode"}},
+		{"€", RadixOptions{Bits: 8, Mode: "unicode"}},
+		{"\xff", RadixOptions{Mode: "unicode"}},
+		{"a", RadixOptions{Radix: 3}},
+		{"a", RadixOptions{Bits: 12}},
+		{"a", RadixOptions{Mode: "utf16"}},
+	}
+	for _, test := range encodes {
+		_, err := EncodeRadix([]byte(test.text), test.opts)
+		assert.NotNil(t, err, "%q %+v", test.text, test.opts)
+	}
+	// 😀 needs a surrogate pair at 16 bits, so only 32 bits carry it as one group
+	encoded, err := EncodeRadix([]byte("😀"), RadixOptions{Bits: 16, Mode: "unicode", Radix: 16})
+	assert.Nil(t, err)
+	assert.Equal(t, "d83dde00", encoded)
+
+	decodes := []struct {
+		encoded string
+		opts    RadixOptions
+	}{
+		{"10000000", RadixOptions{Bits: 7, Separator: " "}},
+		{"ff", RadixOptions{Radix: 16, Bits: 7}},
+		{"256", RadixOptions{Radix: 10}},
+		{"0100", RadixOptions{Radix: 16, Bits: 16}},
+		{"d800", RadixOptions{Radix: 16, Bits: 32, Separator: " "}},
+		{"0110100", RadixOptions{}},
+		{"12", RadixOptions{Radix: 2, Separator: " "}},
+		{"0x41", RadixOptions{Radix: 16, Separator: " "}},
+	}
+	for _, test := range decodes {
+		_, err := DecodeRadix(test.encoded, test.opts)
+		assert.NotNil(t, err, "%q %+v", test.encoded, test.opts)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const MaxASN1Depth = 64
+
+var asn1TagNames = map[int]string{
+	1:  "BOOLEAN",
+	2:  "INTEGER",
+	3:  "BIT STRING",
+	4:  "OCTET STRING",
+	5:  "NULL",
+	6:  "OBJECT IDENTIFIER",
+	10: "ENUMERATED",
+	12: "UTF8String",
+	16: "SEQUENCE",
+	17: "SET",
+	19: "PrintableString",
+	20: "T61String",
+	22: "IA5String",
+	23: "UTCTime",
+	24: "GeneralizedTime",
+	26: "VisibleString",
+	30: "BMPString",
+}
+
+var asn1ClassNames = []string{"universal", "application", "context", "private"}
+
+var oidNames = map[string]string{
+	"1.2.840.10045.2.1":       "ecPublicKey",
+	"1.2.840.10045.3.1.7":     "prime256v1",
+	"1.3.132.0.10":            "secp256k1",
+	"1.3.132.0.34":            "secp384r1",
+	"1.3.132.0.35":            "secp521r1",
+	"1.2.840.10045.4.3.2":     "ecdsa-with-SHA256",
+	"1.2.840.10045.4.3.3":     "ecdsa-with-SHA384",
+	"1.2.840.113549.1.1.1":    "rsaEncryption",
+	"1.2.840.113549.1.1.11":   "sha256WithRSAEncryption",
+	"1.2.840.113549.1.5.12":   "PBKDF2",
+	"1.2.840.113549.1.5.13":   "PBES2",
+	"1.2.840.113549.1.7.1":    "pkcs7-data",
+	"1.2.840.113549.1.7.2":    "pkcs7-signedData",
+	"1.2.840.113549.1.9.1":    "emailAddress",
+	"1.2.840.113549.2.9":      "hmacWithSHA256",
+	"1.3.101.110":             "X25519",
+	"1.3.101.112":             "Ed25519",
+	"2.16.840.1.101.3.4.1.2":  "aes-128-cbc",
+	"2.16.840.1.101.3.4.1.42": "aes-256-cbc",
+	"2.16.840.1.101.3.4.2.1":  "sha256",
+	"2.16.840.1.101.3.4.2.3":  "sha512",
+	"2.5.4.3":                 "commonName",
+	"2.5.4.6":                 "countryName",
+	"2.5.4.7":                 "localityName",
+	"2.5.4.8":                 "stateOrProvinceName",
+	"2.5.4.10":                "organizationName",
+	"2.5.4.11":                "organizationalUnitName",
+	"2.5.29.14":               "subjectKeyIdentifier",
+	"2.5.29.15":               "keyUsage",
+	"2.5.29.17":               "subjectAltName",
+	"2.5.29.19":               "basicConstraints",
+	"2.5.29.35":               "authorityKeyIdentifier",
+	"2.5.29.37":               "extKeyUsage",
+	"1.2.156.10197.1.301":     "SM2",
+	"1.2.156.10197.1.301.1":   "sm2sign",
+	"1.2.156.10197.1.301.2":   "sm2exchange",
+	"1.2.156.10197.1.301.3":   "sm2encrypt",
+	"1.2.156.10197.1.401":     "SM3",
+	"1.2.156.10197.1.401.1":   "sm3",
+	"1.2.156.10197.1.401.3.1": "hmac-sm3",
+	"1.2.156.10197.1.501":     "SM2-with-SM3",
+	"1.2.156.10197.1.104":     "SM4",
+	"1.2.156.10197.1.104.1":   "sm4-ecb",
+	"1.2.156.10197.1.104.2":   "sm4-cbc",
+	"1.2.156.10197.1.104.3":   "sm4-ofb",
+	"1.2.156.10197.1.104.4":   "sm4-cfb",
+	"1.2.156.10197.1.104.7":   "sm4-ctr",
+	"1.2.156.10197.1.104.8":   "sm4-gcm",
+	"1.2.156.10197.6.1.4.2.1": "sm2-data",
+	"1.2.156.10197.6.1.4.2.2": "sm2-signedData",
+	"1.2.156.10197.6.1.4.2.3": "sm2-envelopedData",
+}
+
+type ASN1Node struct {
+	Offset       int         `json:"offset"`
+	HeaderLength int         `json:"header_length"`
+	Length       int         `json:"length"`
+	Class        string      `json:"class"`
+	Tag          int         `json:"tag"`
+	TagName      string      `json:"tag_name,omitempty"`
+	Constructed  bool        `json:"constructed"`
+	Value        interface{} `json:"value,omitempty"`
+	OIDName      string      `json:"oid_name,omitempty"`
+	Children     []*ASN1Node `json:"children,omitempty"`
+}
+
+// InspectDER decodes every top-level element of data into a tree
+func InspectDER(data []byte) ([]*ASN1Node, error) {
+	return parseASN1(data, 0, 0)
+}
+
+func parseASN1(data []byte, base, depth int) ([]*ASN1Node, error) {
+	if depth > MaxASN1Depth {
+		return nil, fmt.Errorf("offset %d: nested deeper than %d", base, MaxASN1Depth)
+	}
+	var nodes []*ASN1Node
+	for pos := 0; pos < len(data); {
+		node, content, err := parseASN1Header(data[pos:], base+pos)
+		if err != nil {
+			return nil, err
+		}
+		if node.Constructed {
+			if node.Children, err = parseASN1(content, base+pos+node.HeaderLength, depth+1); err != nil {
+				return nil, err
+			}
+		} else {
+			decodeASN1Primitive(node, content, depth)
+		}
+		nodes = append(nodes, node)
+		pos += node.HeaderLength + node.Length
+	}
+	return nodes, nil
+}
+
+func parseASN1Header(data []byte, offset int) (*ASN1Node, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, fmt.Errorf("offset %d: truncated header", offset)
+	}
+	node := &ASN1Node{
+		Offset:      offset,
+		Class:       asn1ClassNames[data[0]>>6],
+		Constructed: data[0]&0x20 != 0,
+		Tag:         int(data[0] & 0x1f),
+	}
+	pos := 1
+	if node.Tag == 0x1f {
+		//high tag number form
+		node.Tag = 0
+		for {
+			if pos >= len(data) || pos > 4 {
+				return nil, nil, fmt.Errorf("offset %d: invalid high tag number", offset)
+			}
+			b := data[pos]
+			pos++
+			node.Tag = node.Tag<<7 | int(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+	}
+	if pos >= len(data) {
+		return nil, nil, fmt.Errorf("offset %d: truncated length", offset)
+	}
+	length := int(data[pos])
+	pos++
+	if length == 0x80 {
+		return nil, nil, fmt.Errorf("offset %d: indefinite length is not DER", offset)
+	}
+	if length > 0x80 {
+		n := length & 0x7f
+		if n > 4 || pos+n > len(data) {
+			return nil, nil, fmt.Errorf("offset %d: invalid long form length of %d bytes", offset, n)
+		}
+		length = 0
+		for _, b := range data[pos : pos+n] {
+			length = length<<8 | int(b)
+		}
+		pos += n
+	}
+	if length > len(data)-pos {
+		return nil, nil, fmt.Errorf("offset %d: length %d exceeds the %d bytes available", offset, length, len(data)-pos)
+	}
+	node.HeaderLength = pos
+	node.Length = length
+	if node.Class == "universal" {
+		node.TagName = asn1TagNames[node.Tag]
+	}
+	return node, data[pos : pos+length], nil
+}
+
+func decodeASN1Primitive(node *ASN1Node, content []byte, depth int) {
+	if node.Class != "universal" {
+		node.Value = hex.EncodeToString(content)
+		return
+	}
+	switch node.TagName {
+	case "BOOLEAN":
+		node.Value = len(content) == 1 && content[0] != 0
+	case "INTEGER", "ENUMERATED":
+		n := new(big.Int).SetBytes(content)
+		if len(content) > 0 && content[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(content))))
+		}
+		node.Value = n.String()
+	case "NULL":
+	case "OBJECT IDENTIFIER":
+		oid, err := decodeOID(content)
+		if err != nil {
+			node.Value = hex.EncodeToString(content)
+			return
+		}
+		node.Value = oid
+		node.OIDName = oidNames[oid]
+	case "BIT STRING", "OCTET STRING":
+		payload := content
+		if node.TagName == "BIT STRING" && len(payload) > 0 {
+			//the first byte counts unused bits, whole-byte payloads may wrap more DER
+			if payload[0] == 0 {
+				payload = payload[1:]
+			} else {
+				payload = nil
+			}
+		}
+		node.Value = hex.EncodeToString(content)
+		if encapsulated := parseEncapsulated(payload, node.Offset+node.HeaderLength+len(content)-len(payload), depth); encapsulated != nil {
+			node.Children = encapsulated
+		}
+	case "UTF8String", "PrintableString", "IA5String", "T61String", "VisibleString", "UTCTime", "GeneralizedTime":
+		if utf8.Valid(content) {
+			node.Value = string(content)
+		} else {
+			node.Value = hex.EncodeToString(content)
+		}
+	case "BMPString":
+		units := make([]uint16, len(content)/2)
+		for i := range units {
+			units[i] = uint16(content[2*i])<<8 | uint16(content[2*i+1])
+		}
+		node.Value = string(utf16.Decode(units))
+	default:
+		node.Value = hex.EncodeToString(content)
+	}
+}
+
+// parseEncapsulated returns the tree inside a string only when it is a single well formed SEQUENCE or SET
+func parseEncapsulated(payload []byte, base, depth int) []*ASN1Node {
+	if len(payload) < 2 || (payload[0] != 0x30 && payload[0] != 0x31) {
+		return nil
+	}
+	nodes, err := parseASN1(payload, base, depth+1)
+	if err != nil || len(nodes) != 1 {
+		return nil
+	}
+	return nodes
+}
+
+func decodeOID(content []byte) (string, error) {
+	if len(content) == 0 {
+		return "", errors.New("empty oid")
+	}
+	var arcs []string
+	var value uint64
+	for i, b := range content {
+		if value > 1<<56 {
+			return "", errors.New("oid arc too large")
+		}
+		value = value<<7 | uint64(b&0x7f)
+		if b&0x80 != 0 {
+			if i == len(content)-1 {
+				return "", errors.New("truncated oid")
+			}
+			continue
+		}
+		if len(arcs) == 0 {
+			first := value / 40
+			if first > 2 {
+				first = 2
+			}
+			arcs = append(arcs, fmt.Sprint(first), fmt.Sprint(value-40*first))
+		} else {
+			arcs = append(arcs, fmt.Sprint(value))
+		}
+		value = 0
+	}
+	return strings.Join(arcs, "."), nil
+}
+
+type PEMBlock struct {
+	Type    string            `json:"type"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Tree    []*ASN1Node       `json:"tree,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// InspectPEM decodes every PEM block of content, a block that is not DER keeps its error
+func InspectPEM(content string) ([]*PEMBlock, error) {
+	var blocks []*PEMBlock
+	rest := []byte(content)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		b := &PEMBlock{Type: block.Type, Headers: block.Headers}
+		if _, encrypted := block.Headers["DEK-Info"]; encrypted {
+			b.Error = "legacy encrypted block, DEK-Info is required to decrypt it"
+		} else if tree, err := InspectDER(block.Bytes); err != nil {
+			b.Error = err.Error()
+		} else {
+			b.Tree = tree
+		}
+		blocks = append(blocks, b)
+	}
+	if len(blocks) == 0 {
+		return nil, errors.New("no PEM block found")
+	}
+	return blocks, nil
+}
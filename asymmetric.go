@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func AsymmetricContent(ab *AsymmetricBody) (interface{}, error) {
+	switch ab.Method {
+	case "secp256k1":
+		return Secp256k1Content(ab)
+	case "sm2":
+		return SM2Content(ab)
+	case "ed25519":
+		return Ed25519Content(ab)
+	case "x25519", "p256", "p384":
+		return ECDHContent(ecdhCurves[ab.Method], ab)
+	}
+	return nil, fmt.Errorf("invalid crypto method: %s", ab.Method)
+}
+
+func Secp256k1Content(ab *AsymmetricBody) (interface{}, error) {
+	switch ab.Operation {
+	case "generate":
+		privkey, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Privkey string `json:"privkey"`
+			Pubkey  string `json:"pubkey"`
+			Address string `json:"address"`
+		}
+		address := crypto.PubkeyToAddress(privkey.PublicKey)
+		result.Privkey, err = EncodeContent(crypto.FromECDSA(privkey), ab.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		result.Pubkey, _ = EncodeContent(crypto.FromECDSAPub(&privkey.PublicKey), ab.OutputEncoding, EncodingHex)
+		result.Address = address.Hex()
+		return result, nil
+	case "get_address":
+		privBytes, err := DecodeContent("content", ab.Content, ab.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		privkey, err := crypto.ToECDSA(privBytes)
+		if err != nil {
+			return nil, err
+		}
+		address := crypto.PubkeyToAddress(privkey.PublicKey)
+		var result struct {
+			Privkey string `json:"privkey"`
+			Address string `json:"address"`
+		}
+		result.Privkey = ab.Content
+		result.Address = address.Hex()
+		return result, nil
+	case "sign":
+		privBytes, err := DecodeContent("content", ab.Content, ab.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		privkey, err := crypto.ToECDSA(privBytes)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := ab.SignDigest(32)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := Secp256k1Sign(privkey, digest)
+		if err != nil {
+			return nil, err
+		}
+		result := &SignResult{V: sig.V}
+		result.Digest, err = EncodeContent(digest, ab.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		result.Signature, _ = EncodeContent(sig.RSV(), ab.OutputEncoding, EncodingHex)
+		result.Der, _ = EncodeContent(sig.DER(), ab.OutputEncoding, EncodingHex)
+		result.Compact, _ = EncodeContent(sig.Compact(), ab.OutputEncoding, EncodingHex)
+		return result, nil
+	case "verify":
+		pubBytes, err := decodeHex("pubkey", ab.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		pubkey, err := ParseSecp256k1PublicKey(pubBytes)
+		if err != nil {
+			return nil, err
+		}
+		sigBytes, err := decodeHex("signature", ab.Signature)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := ParseSecp256k1Signature(sigBytes, ab.SignatureFormat)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := ab.SignDigest(32)
+		if err != nil {
+			return nil, err
+		}
+		return Secp256k1Verify(pubkey, digest, sig), nil
+	case "recover":
+		sigBytes, err := decodeHex("signature", ab.Signature)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := ParseSecp256k1Signature(sigBytes, ab.SignatureFormat)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := ab.SignDigest(32)
+		if err != nil {
+			return nil, err
+		}
+		pubkey, err := Secp256k1Recover(digest, sig)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Pubkey     string `json:"pubkey"`
+			Compressed string `json:"compressed"`
+			Address    string `json:"address"`
+		}
+		result.Pubkey, err = EncodeContent(crypto.FromECDSAPub(pubkey), ab.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		result.Compressed, _ = EncodeContent(crypto.CompressPubkey(pubkey), ab.OutputEncoding, EncodingHex)
+		result.Address = crypto.PubkeyToAddress(*pubkey).Hex()
+		return result, nil
+	case "derive_shared":
+		privBytes, err := DecodeContent("content", ab.Content, ab.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		privkey, err := crypto.ToECDSA(privBytes)
+		if err != nil {
+			return nil, err
+		}
+		pubBytes, err := decodeHex("pubkey", ab.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		pubkey, err := ParseSecp256k1PublicKey(pubBytes)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := Secp256k1ECDH(privkey, pubkey)
+		if err != nil {
+			return nil, err
+		}
+		key, err := DeriveSharedKey(ab, shared)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeContent(key, ab.OutputEncoding, EncodingHex)
+	}
+	return nil, fmt.Errorf("invalid asymmetric operation: %s", ab.Operation)
+}
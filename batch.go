@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+const (
+	BatchWorkers  = 8
+	MaxBatchItems = 1000
+)
+
+type BatchResult struct {
+	Index int `json:"index"`
+	HttpResult
+}
+
+// IsBatch reports whether the request body is a JSON array
+func IsBatch(reqBytes []byte) bool {
+	trimmed := bytes.TrimSpace(reqBytes)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+func CheckBatch(n int) error {
+	if n > MaxBatchItems {
+		return fmt.Errorf("too many batch items: %d > %d", n, MaxBatchItems)
+	}
+	return nil
+}
+
+// RunBatch runs do for every index on at most BatchWorkers goroutines, results keep the request order
+func RunBatch(n int, do func(i int) (interface{}, error)) []BatchResult {
+	results := make([]BatchResult, n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	workers := BatchWorkers
+	if n < workers {
+		workers = n
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				runBatchItem(&results[i], i, do)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// runBatchItem turns a panic of one item into its error so the other items and the server survive
+func runBatchItem(result *BatchResult, i int, do func(i int) (interface{}, error)) {
+	result.Index = i
+	defer func() {
+		if r := recover(); r != nil {
+			result.Error = fmt.Sprint(r)
+		}
+	}()
+	value, err := do(i)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	result.Result = value
+}
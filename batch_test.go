@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunBatchPanic(t *testing.T) {
+	results := RunBatch(3, func(i int) (interface{}, error) {
+		switch i {
+		case 1:
+			panic("boom")
+		case 2:
+			return nil, errors.New("bad item")
+		}
+		return "ok", nil
+	})
+	assert.Equal(t, 3, len(results))
+	for i, r := range results {
+		assert.Equal(t, i, r.Index)
+	}
+	assert.Equal(t, "ok", results[0].Result)
+	assert.Equal(t, "", results[0].Error)
+	assert.Nil(t, results[1].Result)
+	assert.Equal(t, "boom", results[1].Error)
+	assert.Equal(t, "bad item", results[2].Error)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+func base58Checksum(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
+
+func Base58CheckEncode(version byte, payload []byte) string {
+	data := append([]byte{version}, payload...)
+	return base58.Encode(append(data, base58Checksum(data)...))
+}
+
+func Base58CheckDecode(content string) (version byte, payload []byte, err error) {
+	data, err := DecodeBase58(content)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) < 5 {
+		return 0, nil, fmt.Errorf("base58check too short: %d bytes, want version byte + payload + 4 checksum bytes", len(data))
+	}
+	body, checksum := data[:len(data)-4], data[len(data)-4:]
+	if expected := base58Checksum(body); !bytes.Equal(expected, checksum) {
+		return 0, nil, fmt.Errorf("base58check checksum mismatch: got %x, expected %x", checksum, expected)
+	}
+	return body[0], body[1:], nil
+}
+
+type Bech32Result struct {
+	Hrp            string `json:"hrp"`
+	WitnessVersion *int   `json:"witness_version,omitempty"`
+	Data           string `json:"data"`
+}
+
+func bech32Variant(method string) bech32.Version {
+	if method == "bech32m" {
+		return bech32.VersionM
+	}
+	return bech32.Version0
+}
+
+func checkWitness(witnessVersion int, program []byte, variant bech32.Version) error {
+	if witnessVersion < 0 || witnessVersion > 16 {
+		return fmt.Errorf("invalid witness version: %d", witnessVersion)
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return fmt.Errorf("invalid witness program length: %d bytes, want 2 to 40", len(program))
+	}
+	if witnessVersion == 0 && len(program) != 20 && len(program) != 32 {
+		return fmt.Errorf("invalid witness v0 program length: %d bytes, want 20 or 32", len(program))
+	}
+	if witnessVersion == 0 && variant != bech32.Version0 {
+		return fmt.Errorf("witness v0 must use bech32, not bech32m")
+	}
+	if witnessVersion != 0 && variant != bech32.VersionM {
+		return fmt.Errorf("witness v%d must use bech32m, not bech32", witnessVersion)
+	}
+	return nil
+}
+
+// Bech32Encode encodes data under hrp, prefixed with the witness version for segwit addresses
+func Bech32Encode(method, hrp string, data []byte, segwit bool, witnessVersion int) (string, error) {
+	variant := bech32Variant(method)
+	if segwit {
+		if err := checkWitness(witnessVersion, data, variant); err != nil {
+			return "", err
+		}
+	}
+	converted, err := bech32.ConvertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	if segwit {
+		converted = append([]byte{byte(witnessVersion)}, converted...)
+	}
+	if variant == bech32.VersionM {
+		return bech32.EncodeM(hrp, converted)
+	}
+	return bech32.Encode(hrp, converted)
+}
+
+func Bech32Decode(method, content string, segwit bool, outputEncoding string) (*Bech32Result, error) {
+	hrp, data, variant, err := bech32.DecodeNoLimitWithVersion(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", method, err)
+	}
+	if variant != bech32Variant(method) {
+		return nil, fmt.Errorf("checksum is %s, not %s", bech32Name(variant), method)
+	}
+	result := &Bech32Result{Hrp: hrp}
+	if segwit {
+		if len(content) > 90 {
+			return nil, fmt.Errorf("segwit address too long: %d characters, want at most 90", len(content))
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("no witness version")
+		}
+		witnessVersion := int(data[0])
+		result.WitnessVersion = &witnessVersion
+		data = data[1:]
+	}
+	decoded, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s data: %v", method, err)
+	}
+	if segwit {
+		if err = checkWitness(*result.WitnessVersion, decoded, variant); err != nil {
+			return nil, err
+		}
+	}
+	result.Data, err = EncodeContent(decoded, outputEncoding, EncodingHex)
+	return result, err
+}
+
+func bech32Name(variant bech32.Version) string {
+	if variant == bech32.VersionM {
+		return "bech32m"
+	}
+	return "bech32"
+}
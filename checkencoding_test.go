@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vectors of bitcoin core src/test/data/base58_encode_decode.json
+func TestBase58(t *testing.T) {
+	tests := [][2]string{
+		{"", ""},
+		{"61", "2g"},
+		{"626262", "a3gV"},
+		{"636363", "aPEr"},
+		{"73696d706c792061206c6f6e6720737472696e67", "2cFupjhnEsSn59qHXstmK2ffpLv2"},
+		{"00eb15231dfceb60925886b67d065299925915aeb172c06647", "1NS17iag9jJgTHD1VXjvLCEnZuQ3rJDE9L"},
+		{"516b6fcd0f", "ABnLTmg"},
+		{"bf4f89001e670274dd", "3SEo3LWLoPntC"},
+		{"572e4794", "3EFU7m"},
+		{"ecac89cad93923c02321", "EJDM8drfXA6uyA"},
+		{"10c8511e", "Rt5zm"},
+		{"00000000000000000000", "1111111111"},
+	}
+	for _, test := range tests {
+		encoded, err := CodecContent(&CodecBody{Method: "base58_encode", Content: test[0], ContentEncoding: ContentEncoding{InputEncoding: EncodingHex}})
+		assert.Nil(t, err)
+		assert.Equal(t, test[1], encoded)
+		decoded, err := CodecContent(&CodecBody{Method: "base58_decode", Content: test[1], ContentEncoding: ContentEncoding{OutputEncoding: EncodingHex}})
+		assert.Nil(t, err)
+		assert.Equal(t, test[0], decoded)
+	}
+	for _, invalid := range []string{"0", "O", "I", "l", "3mJr0", "3yxU+"} {
+		_, err := CodecContent(&CodecBody{Method: "base58_decode", Content: invalid})
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+// addresses and WIF keys of private key 1
+func TestBase58Check(t *testing.T) {
+	tests := []struct {
+		version int
+		payload string
+		encoded string
+	}{
+		{0, "751e76e8199196d454941c45d1b3a323f1433bd6", "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH"},
+		{0, "91b24bf9f5288532960ac687abb035127b1d28a5", "1EHNa6Q4Jz2uvNExL497mE43ikXhwF6kZm"},
+		{0x80, "0000000000000000000000000000000000000000000000000000000000000001", "5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreAnchuDf"},
+		{0x80, "000000000000000000000000000000000000000000000000000000000000000101", "KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn"},
+	}
+	for _, test := range tests {
+		encoded, err := CodecContent(&CodecBody{Method: "base58check_encode", Content: test.payload, Version: test.version})
+		assert.Nil(t, err)
+		assert.Equal(t, test.encoded, encoded)
+
+		version, payload, err := Base58CheckDecode(test.encoded)
+		assert.Nil(t, err)
+		assert.Equal(t, test.version, int(version))
+		assert.Equal(t, test.payload, hex.EncodeToString(payload))
+	}
+
+	_, _, err := Base58CheckDecode("1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMJ")
+	assert.ErrorContains(t, err, "checksum mismatch")
+	_, _, err = Base58CheckDecode("2g")
+	assert.ErrorContains(t, err, "too short")
+	_, err = CodecContent(&CodecBody{Method: "base58check_encode", Content: "00", Version: 256})
+	assert.NotNil(t, err)
+}
+
+// BIP-173 and BIP-350 test vectors
+func TestBech32Checksum(t *testing.T) {
+	valid := map[string][]string{
+		"bech32": {
+			"A12UEL5L",
+			"a12uel5l",
+			"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+			"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+			"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+			"?1ezyfcl",
+		},
+		"bech32m": {
+			"A1LQFN3A",
+			"a1lqfn3a",
+			"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx",
+			"split1checkupstagehandshakeupstreamerranterredcaperredlc445v",
+			"?1v759aa",
+		},
+	}
+	invalid := map[string][]string{
+		"bech32": {
+			"pzry9x0s0muk",
+			"1pzry9x0s0muk",
+			"x1b4n0q5v",
+			"li1dgmt3",
+			"A1G7SGD8",
+			"10a06t8",
+			"1qzzfhee",
+		},
+		"bech32m": {
+			"qyrz8wqd2c9m",
+			"1qyrz8wqd2c9m",
+			"y1b0jsk6g",
+			"lt1igcx5c0",
+			"in1muywd",
+			"M1VUXWEZ",
+			"16plkw9",
+			"1p2gdwpf",
+		},
+	}
+	for method, inputs := range valid {
+		other := "bech32m"
+		if method == "bech32m" {
+			other = "bech32"
+		}
+		for _, input := range inputs {
+			result, err := Bech32Decode(method, input, false, EncodingHex)
+			if assert.Nil(t, err, input) {
+				assert.Equal(t, strings.ToLower(input[:strings.LastIndex(input, "1")]), result.Hrp)
+			}
+			_, err = Bech32Decode(other, input, false, EncodingHex)
+			assert.NotNil(t, err, "%s as %s", input, other)
+		}
+	}
+	for method, inputs := range invalid {
+		for _, input := range inputs {
+			_, err := Bech32Decode(method, input, false, EncodingHex)
+			assert.NotNil(t, err, input)
+		}
+	}
+}
+
+func TestBech32Segwit(t *testing.T) {
+	valid := []struct {
+		address        string
+		witnessVersion int
+		program        string
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", 0, "751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", 0, "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", 1, "751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", 16, "751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", 2, "751e76e8199196d454941c45d1b3a323"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", 1, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+	for _, test := range valid {
+		method := "bech32m"
+		if test.witnessVersion == 0 {
+			method = "bech32"
+		}
+		result, err := CodecContent(&CodecBody{Method: method + "_decode", Content: test.address, Segwit: true})
+		if !assert.Nil(t, err, test.address) {
+			continue
+		}
+		decoded := result.(*Bech32Result)
+		assert.Equal(t, test.witnessVersion, *decoded.WitnessVersion)
+		assert.Equal(t, test.program, decoded.Data)
+
+		encoded, err := CodecContent(&CodecBody{Method: method + "_encode", Content: test.program, Hrp: decoded.Hrp, Segwit: true, WitnessVersion: test.witnessVersion})
+		assert.Nil(t, err)
+		assert.Equal(t, strings.ToLower(test.address), encoded)
+	}
+
+	invalid := map[string]string{
+		// v1 with a bech32 checksum, v0 with a bech32m checksum
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd": "bech32",
+		"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P":                           "bech32",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh":                     "bech32m",
+		"bc1gmk9yu":                                  "bech32",
+		"bc1zw508d6qejxtdg4y5r3zarvaryvqyzf3du":      "bech32m",
+		"tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k": "bech32m",
+		"bc1rw5uspcuh":                               "bech32m",
+		"bc10w508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kw5rljs90": "bech32m",
+	}
+	for address, method := range invalid {
+		_, err := CodecContent(&CodecBody{Method: method + "_decode", Content: address, Segwit: true})
+		assert.NotNil(t, err, address)
+	}
+	_, err := CodecContent(&CodecBody{Method: "bech32m_encode", Content: "751e76e8199196d454941c45d1b3a323f1433bd6", Hrp: "bc", Segwit: true})
+	assert.NotNil(t, err)
+}
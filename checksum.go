@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/crc64"
+	"hash/fnv"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+var (
+	castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+	crc64ECMATable  = crc64.MakeTable(crc64.ECMA)
+	crc64ISOTable   = crc64.MakeTable(crc64.ISO)
+)
+
+// non-cryptographic checksums, kept apart from hashMethods so they are never taken for secure digests
+var checksumMethods = map[string]func() hash.Hash{
+	"crc32":     func() hash.Hash { return crc32.NewIEEE() },
+	"crc32c":    func() hash.Hash { return crc32.New(castagnoliTable) },
+	"crc64":     func() hash.Hash { return crc64.New(crc64ECMATable) },
+	"crc64_iso": func() hash.Hash { return crc64.New(crc64ISOTable) },
+	"adler32":   func() hash.Hash { return adler32.New() },
+	"fnv1a_32":  func() hash.Hash { return fnv.New32a() },
+	"fnv1a_64":  func() hash.Hash { return fnv.New64a() },
+	"xxhash64":  func() hash.Hash { return xxhash.New() },
+}
+
+func ChecksumSum(method string, data []byte) ([]byte, error) {
+	newHash, ok := checksumMethods[method]
+	if !ok {
+		return nil, fmt.Errorf("invalid checksum method: %s", method)
+	}
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// bsdTags are the algorithm names of tagged lines, as coreutils cksum --tag, shasum --tag and xxhsum --tag print them
+var bsdTags = map[string]string{
+	"md5":        "MD5",
+	"sha1":       "SHA1",
+	"sha224":     "SHA224",
+	"sha256":     "SHA256",
+	"sha384":     "SHA384",
+	"sha512":     "SHA512",
+	"sha512_256": "SHA512/256",
+	"sha3_256":   "SHA3-256",
+	"sha3_512":   "SHA3-512",
+	"keccak256":  "KECCAK-256",
+	"sm3":        "SM3",
+	"ripemd160":  "RMD160",
+	"hash160":    "HASH160",
+	"blake2b":    "BLAKE2b",
+	"blake2s":    "BLAKE2s",
+	"blake3":     "BLAKE3",
+	"crc32":      "CRC32",
+	"crc32c":     "CRC32C",
+	"crc64":      "CRC64",
+	"crc64_iso":  "CRC64-ISO",
+	"adler32":    "ADLER32",
+	"fnv1a_32":   "FNV1A-32",
+	"fnv1a_64":   "FNV1A-64",
+	"xxhash64":   "XXH64",
+}
+
+// bsdTag is the tag of method, blake2b shorter than 64 bytes is BLAKE2b-<bits> as cksum -l prints it
+func bsdTag(method string, size int) (string, error) {
+	tag, ok := bsdTags[method]
+	if !ok {
+		return "", fmt.Errorf("bsd format has no tag for %s", method)
+	}
+	if method == "blake2b" && size != 64 {
+		tag = fmt.Sprintf("%s-%d", tag, size*8)
+	}
+	return tag, nil
+}
+
+// FormatSumLine renders a sum of size bytes as one line of a checksum file:
+// gnu is "<sum>  <file>" as sha256sum prints, bsd is "SHA256 (<file>) = <sum>" as sha256sum --tag prints
+func FormatSumLine(format, method string, size int, sum, filename string) (string, error) {
+	if filename == "" {
+		filename = "-"
+	}
+	switch format {
+	case "":
+		return sum, nil
+	case "gnu":
+		return fmt.Sprintf("%s  %s", sum, filename), nil
+	case "bsd":
+		tag, err := bsdTag(method, size)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s (%s) = %s", tag, filename, sum), nil
+	}
+	return "", fmt.Errorf("invalid format: %s", format)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lines printed for a file named abc holding "abc" by cksum --tag -a <method> (coreutils 9.1, sha3 from 9.7)
+// and shasum -a 512256 --tag
+func TestFormatSumLineCoreutils(t *testing.T) {
+	tests := []struct {
+		method string
+		size   int
+		line   string
+	}{
+		{"md5", 0, "MD5 (abc) = 900150983cd24fb0d6963f7d28e17f72"},
+		{"sha1", 0, "SHA1 (abc) = a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{"sha224", 0, "SHA224 (abc) = 23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7"},
+		{"sha256", 0, "SHA256 (abc) = ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"sha384", 0, "SHA384 (abc) = cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7"},
+		{"sha512", 0, "SHA512 (abc) = ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+		{"sha512_256", 0, "SHA512/256 (abc) = 53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23"},
+		{"sha3_256", 0, "SHA3-256 (abc) = 3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{"blake2b", 0, "BLAKE2b (abc) = ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{"blake2b", 32, "BLAKE2b-256 (abc) = bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+		{"sm3", 0, "SM3 (abc) = 66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	}
+	for _, test := range tests {
+		line, err := HashContent(&HashBody{Method: test.method, Content: "abc", Format: "bsd", Filename: "abc", Size: test.size})
+		assert.Nil(t, err)
+		assert.Equal(t, test.line, line)
+	}
+}
+
+func TestBsdTagEveryMethod(t *testing.T) {
+	for method := range hashMethods {
+		_, err := HashContent(&HashBody{Method: method, Content: "abc", Format: "bsd"})
+		assert.Nil(t, err, method)
+	}
+	for method := range checksumMethods {
+		_, err := HashContent(&HashBody{Method: method, Operation: "checksum", Content: "abc", Format: "bsd"})
+		assert.Nil(t, err, method)
+	}
+	line, err := HashContent(&HashBody{Method: "xxhash64", Operation: "checksum", Content: "abc", Format: "bsd", Filename: "abc"})
+	assert.Nil(t, err)
+	assert.Equal(t, "XXH64 (abc) = 44bc2cf5ad770999", line)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+var radixFlags = []cli.Flag{
+	cli.IntFlag{
+		Name:  "radix, r",
+		Value: 2,
+		Usage: "2, 8, 10 or 16",
+	},
+	cli.IntFlag{
+		Name:  "bits, b",
+		Value: 8,
+		Usage: "group width: 7, 8, 16 or 32",
+	},
+	cli.StringFlag{
+		Name:  "mode, m",
+		Value: "utf8",
+		Usage: "utf8 (one group per byte) or unicode (one group per code point, UTF-16 unit with -b 16)",
+	},
+	cli.StringFlag{
+		Name:  "separator, s",
+		Usage: "between groups, none by default",
+	},
+}
+
+var asciiCommand = cli.Command{
+	Name:  "ascii",
+	Usage: "encode text as binary/octal/decimal/hex digit groups and back",
+	Subcommands: []cli.Command{
+		{
+			Name:      "encode",
+			Usage:     "text to digit groups",
+			ArgsUsage: "[text], read from stdin when empty",
+			Flags:     radixFlags,
+			Action: func(ctx *cli.Context) error {
+				input, err := cliInput(ctx)
+				if err != nil {
+					return err
+				}
+				result, err := EncodeRadix(input, radixOptions(ctx))
+				if err != nil {
+					return err
+				}
+				fmt.Println(result)
+				return nil
+			},
+		},
+		{
+			Name:      "decode",
+			Usage:     "digit groups to text",
+			ArgsUsage: "[digits], read from stdin when empty",
+			Flags:     radixFlags,
+			Action: func(ctx *cli.Context) error {
+				input, err := cliInput(ctx)
+				if err != nil {
+					return err
+				}
+				result, err := DecodeRadix(string(input), radixOptions(ctx))
+				if err != nil {
+					return err
+				}
+				os.Stdout.Write(result)
+				return nil
+			},
+		},
+	},
+}
+
+func radixOptions(ctx *cli.Context) RadixOptions {
+	return RadixOptions{
+		Radix:     ctx.Int("radix"),
+		Bits:      ctx.Int("bits"),
+		Mode:      ctx.String("mode"),
+		Separator: ctx.String("separator"),
+	}
+}
+
+func cliInput(ctx *cli.Context) ([]byte, error) {
+	if ctx.NArg() > 0 {
+		return []byte(strings.Join(ctx.Args(), " ")), nil
+	}
+	input, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	if len(input) == 0 {
+		return nil, errors.New("no input")
+	}
+	return input, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func CodecContent(cb *CodecBody) (interface{}, error) {
+	if name := strings.TrimSuffix(cb.Method, "_encode"); name != cb.Method && textCodecs[name] != nil {
+		content, err := DecodeContent("content", cb.Content, cb.InputEncoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		return textCodecs[name].Encode(content), nil
+	}
+	if name := strings.TrimSuffix(cb.Method, "_decode"); name != cb.Method && textCodecs[name] != nil {
+		decoded, err := textCodecs[name].Decode(cb.Content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		return EncodeContent(decoded, cb.OutputEncoding, EncodingUTF8)
+	}
+	if name := strings.TrimSuffix(cb.Method, "_compress"); name != cb.Method && compressors[name] != nil {
+		return CompressContent(name, false, cb)
+	}
+	if name := strings.TrimSuffix(cb.Method, "_decompress"); name != cb.Method && compressors[name] != nil {
+		return CompressContent(name, true, cb)
+	}
+	switch cb.Method {
+	case "auto_decode":
+		return AutoDecode(cb.Content, cb.OutputEncoding)
+	case "rlp_encode":
+		value := cb.Value
+		if len(value) == 0 {
+			value = json.RawMessage(cb.Content)
+		}
+		encoded, err := RLPEncode(value)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeContent(encoded, cb.OutputEncoding, EncodingHex)
+	case "rlp_decode":
+		content, err := DecodeContent("content", strings.TrimPrefix(cb.Content, "0x"), cb.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		return RLPDecode(content)
+	case "base58check_encode":
+		content, err := DecodeContent("content", cb.Content, cb.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		if cb.Version < 0 || cb.Version > 255 {
+			return nil, fmt.Errorf("invalid base58check version: %d", cb.Version)
+		}
+		return Base58CheckEncode(byte(cb.Version), content), nil
+	case "base58check_decode":
+		version, payload, err := Base58CheckDecode(cb.Content)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Version int    `json:"version"`
+			Payload string `json:"payload"`
+		}
+		result.Version = int(version)
+		result.Payload, err = EncodeContent(payload, cb.OutputEncoding, EncodingHex)
+		return result, err
+	case "bech32_encode", "bech32m_encode":
+		content, err := DecodeContent("content", cb.Content, cb.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		return Bech32Encode(strings.TrimSuffix(cb.Method, "_encode"), cb.Hrp, content, cb.Segwit, cb.WitnessVersion)
+	case "radix_encode":
+		content, err := DecodeContent("content", cb.Content, cb.InputEncoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeRadix(content, cb.radixOptions())
+	case "radix_decode":
+		decoded, err := DecodeRadix(cb.Content, cb.radixOptions())
+		if err != nil {
+			return nil, err
+		}
+		return EncodeContent(decoded, cb.OutputEncoding, EncodingUTF8)
+	case "der_inspect":
+		content, err := DecodeContent("content", cb.Content, cb.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		return InspectDER(content)
+	case "pem_inspect":
+		return InspectPEM(cb.Content)
+	case "protobuf_decode":
+		content, err := DecodeContent("content", cb.Content, cb.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		if cb.DescriptorSet != "" {
+			return DecodeProtobufTyped(content, cb.DescriptorSet, cb.MessageType)
+		}
+		return DecodeProtobufWire(content)
+	case "bech32_decode", "bech32m_decode":
+		return Bech32Decode(strings.TrimSuffix(cb.Method, "_decode"), cb.Content, cb.Segwit, cb.OutputEncoding)
+	}
+	return nil, fmt.Errorf("invalid crypto method: %s", cb.Method)
+}
+
+func (cb *CodecBody) radixOptions() RadixOptions {
+	return RadixOptions{Radix: cb.Radix, Bits: cb.Bits, Mode: cb.Mode, Separator: cb.Separator}
+}
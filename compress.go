@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// MaxDecompressedSize caps every decompression so a small bomb can't exhaust memory
+const MaxDecompressedSize = 32 << 20
+
+// snappyStreamMagic starts the framed snappy format, anything else is a raw block
+var snappyStreamMagic = []byte("\xff\x06\x00\x00sNaPpY")
+
+type Compressor struct {
+	// MinLevel and MaxLevel bound the level, 0 always selects the default
+	MinLevel   int
+	MaxLevel   int
+	Compress   func(data []byte, level int) ([]byte, error)
+	Decompress func(data []byte) ([]byte, error)
+}
+
+type CompressResult struct {
+	Output     string `json:"output"`
+	InputSize  int    `json:"input_size"`
+	OutputSize int    `json:"output_size"`
+	// Ratio is uncompressed size / compressed size
+	Ratio float64 `json:"ratio"`
+}
+
+var compressors = map[string]*Compressor{
+	"gzip": {
+		MinLevel: flate.HuffmanOnly, MaxLevel: flate.BestCompression,
+		Compress: func(data []byte, level int) ([]byte, error) {
+			return writeCompressed(data, func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriterLevel(w, flateLevel(level))
+			})
+		},
+		Decompress: func(data []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return readDecompressed(r)
+		},
+	},
+	"zlib": {
+		MinLevel: flate.HuffmanOnly, MaxLevel: flate.BestCompression,
+		Compress: func(data []byte, level int) ([]byte, error) {
+			return writeCompressed(data, func(w io.Writer) (io.WriteCloser, error) {
+				return zlib.NewWriterLevel(w, flateLevel(level))
+			})
+		},
+		Decompress: func(data []byte) ([]byte, error) {
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return readDecompressed(r)
+		},
+	},
+	"deflate": {
+		MinLevel: flate.HuffmanOnly, MaxLevel: flate.BestCompression,
+		Compress: func(data []byte, level int) ([]byte, error) {
+			return writeCompressed(data, func(w io.Writer) (io.WriteCloser, error) {
+				return flate.NewWriter(w, flateLevel(level))
+			})
+		},
+		Decompress: func(data []byte) ([]byte, error) {
+			r := flate.NewReader(bytes.NewReader(data))
+			defer r.Close()
+			return readDecompressed(r)
+		},
+	},
+	"snappy": {
+		Compress: func(data []byte, level int) ([]byte, error) {
+			return snappy.Encode(nil, data), nil
+		},
+		Decompress: func(data []byte) ([]byte, error) {
+			if bytes.HasPrefix(data, snappyStreamMagic) {
+				return readDecompressed(snappy.NewReader(bytes.NewReader(data)))
+			}
+			n, err := snappy.DecodedLen(data)
+			if err != nil {
+				return nil, err
+			}
+			if n > MaxDecompressedSize {
+				return nil, fmt.Errorf("decompressed size %d exceeds limit %d", n, MaxDecompressedSize)
+			}
+			return snappy.Decode(nil, data)
+		},
+	},
+	"zstd": {
+		MinLevel: 1, MaxLevel: 22,
+		Compress: func(data []byte, level int) ([]byte, error) {
+			if level == 0 {
+				level = 3
+			}
+			return writeCompressed(data, func(w io.Writer) (io.WriteCloser, error) {
+				return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1))
+			})
+		},
+		Decompress: func(data []byte) ([]byte, error) {
+			r, err := zstd.NewReader(bytes.NewReader(data),
+				zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(MaxDecompressedSize))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return readDecompressed(r)
+		},
+	},
+}
+
+func flateLevel(level int) int {
+	if level == 0 {
+		return flate.DefaultCompression
+	}
+	return level
+}
+
+func writeCompressed(data []byte, newWriter func(w io.Writer) (io.WriteCloser, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readDecompressed reads one byte past the limit to tell a full read from a truncated one
+func readDecompressed(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxDecompressedSize {
+		return nil, fmt.Errorf("decompressed size exceeds limit %d", MaxDecompressedSize)
+	}
+	return data, nil
+}
+
+func NewCompressor(method string) (*Compressor, error) {
+	c, ok := compressors[method]
+	if !ok {
+		return nil, fmt.Errorf("invalid compression method: %s", method)
+	}
+	return c, nil
+}
+
+func Compress(method string, data []byte, level int) ([]byte, error) {
+	c, err := NewCompressor(method)
+	if err != nil {
+		return nil, err
+	}
+	if level != 0 && c.MaxLevel == 0 {
+		return nil, fmt.Errorf("%s has no compression levels", method)
+	}
+	if level != 0 && (level < c.MinLevel || level > c.MaxLevel) {
+		return nil, fmt.Errorf("invalid %s level: %d, want %d..%d", method, level, c.MinLevel, c.MaxLevel)
+	}
+	return c.Compress(data, level)
+}
+
+func Decompress(method string, data []byte) ([]byte, error) {
+	c, err := NewCompressor(method)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := c.Decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s data: %v", method, err)
+	}
+	return decompressed, nil
+}
+
+func compressResult(in, out []byte, uncompressed, compressed int, outputEncoding, defaultEncoding string) (*CompressResult, error) {
+	output, err := EncodeContent(out, outputEncoding, defaultEncoding)
+	if err != nil {
+		return nil, err
+	}
+	result := &CompressResult{Output: output, InputSize: len(in), OutputSize: len(out)}
+	if compressed > 0 {
+		result.Ratio = float64(uncompressed) / float64(compressed)
+	}
+	return result, nil
+}
+
+// CompressContent handles <method>_compress (utf8 in, base64 out) and <method>_decompress (base64 in, utf8 out)
+func CompressContent(method string, decompress bool, cb *CodecBody) (*CompressResult, error) {
+	if decompress {
+		content, err := DecodeContent("content", cb.Content, cb.InputEncoding, EncodingBase64)
+		if err != nil {
+			return nil, err
+		}
+		decompressed, err := Decompress(method, content)
+		if err != nil {
+			return nil, err
+		}
+		return compressResult(content, decompressed, len(decompressed), len(content), cb.OutputEncoding, EncodingUTF8)
+	}
+	content, err := DecodeContent("content", cb.Content, cb.InputEncoding, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := Compress(method, content, cb.Level)
+	if err != nil {
+		return nil, err
+	}
+	return compressResult(content, compressed, len(content), len(compressed), cb.OutputEncoding, EncodingBase64)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog ", 200))
+	for method, c := range compressors {
+		levels := []int{0}
+		for level := c.MinLevel; level <= c.MaxLevel && c.MaxLevel != 0; level++ {
+			levels = append(levels, level)
+		}
+		for _, level := range levels {
+			compressed, err := Compress(method, data, level)
+			if !assert.Nil(t, err, "%s %d", method, level) {
+				continue
+			}
+			assert.Less(t, len(compressed), len(data), "%s %d", method, level)
+			decompressed, err := Decompress(method, compressed)
+			assert.Nil(t, err, "%s %d", method, level)
+			assert.Equal(t, data, decompressed, "%s %d", method, level)
+		}
+	}
+
+	//framed snappy as written by other tools
+	var framed bytes.Buffer
+	w := snappy.NewBufferedWriter(&framed)
+	w.Write(data)
+	w.Close()
+	decompressed, err := Decompress("snappy", framed.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestCompressLevels(t *testing.T) {
+	for _, test := range []struct {
+		method string
+		level  int
+		err    string
+	}{
+		{"gzip", 10, "invalid gzip level: 10, want -2..9"},
+		{"deflate", -3, "invalid deflate level: -3, want -2..9"},
+		{"zstd", 23, "invalid zstd level: 23, want 1..22"},
+		{"snappy", 1, "snappy has no compression levels"},
+	} {
+		_, err := Compress(test.method, []byte("data"), test.level)
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func TestDecompressBomb(t *testing.T) {
+	over := make([]byte, MaxDecompressedSize+1)
+	var framed bytes.Buffer
+	w := snappy.NewBufferedWriter(&framed)
+	w.Write(over)
+	w.Close()
+
+	bombs := map[string][]byte{"snappy framed": framed.Bytes(), "snappy raw": snappy.Encode(nil, over)}
+	for _, method := range []string{"gzip", "zlib", "deflate", "zstd"} {
+		compressed, err := Compress(method, over, 0)
+		assert.Nil(t, err)
+		bombs[method] = compressed
+	}
+	for name, bomb := range bombs {
+		method := strings.Fields(name)[0]
+		assert.Less(t, len(bomb), MaxDecompressedSize/10, name)
+		_, err := Decompress(method, bomb)
+		assert.ErrorContains(t, err, fmt.Sprintf("invalid %s data: ", method), name)
+		assert.ErrorContains(t, err, "exceeds", name)
+	}
+
+	//the limit itself still decompresses
+	compressed, err := Compress("gzip", over[:MaxDecompressedSize], 0)
+	assert.Nil(t, err)
+	decompressed, err := Decompress("gzip", compressed)
+	assert.Nil(t, err)
+	assert.Equal(t, MaxDecompressedSize, len(decompressed))
+}
+
+func TestCompressContentRatio(t *testing.T) {
+	content := strings.Repeat("a", 1000)
+	compressed, err := CompressContent("gzip", false, &CodecBody{Content: content, Level: 9})
+	assert.Nil(t, err)
+	raw, err := base64.StdEncoding.DecodeString(compressed.Output)
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, compressed.InputSize)
+	assert.Equal(t, len(raw), compressed.OutputSize)
+	assert.Equal(t, 1000/float64(len(raw)), compressed.Ratio)
+	assert.Greater(t, compressed.Ratio, 10.0)
+
+	decompressed, err := CompressContent("gzip", true, &CodecBody{Content: compressed.Output})
+	assert.Nil(t, err)
+	assert.Equal(t, content, decompressed.Output)
+	assert.Equal(t, len(raw), decompressed.InputSize)
+	assert.Equal(t, 1000, decompressed.OutputSize)
+	//ratio stays uncompressed / compressed in both directions
+	assert.Equal(t, compressed.Ratio, decompressed.Ratio)
+
+	empty, err := CompressContent("snappy", true, &CodecBody{Content: ""})
+	assert.ErrorContains(t, err, "invalid snappy data")
+	assert.Nil(t, empty)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type KeyPairResult struct {
+	Privkey string `json:"privkey,omitempty"`
+	Pubkey  string `json:"pubkey"`
+}
+
+// FormatKeyPair writes an ed25519 or ecdh key as raw (in output_encoding, hex by default),
+// pem (PKCS#8 and SPKI) or openssh, priv may be nil to export the public key only
+func FormatKeyPair(priv crypto.PrivateKey, pub crypto.PublicKey, format, outputEncoding string) (*KeyPairResult, error) {
+	result := &KeyPairResult{}
+	switch format {
+	case "", "raw":
+		var privBytes, pubBytes []byte
+		switch key := pub.(type) {
+		case ed25519.PublicKey:
+			pubBytes = key
+		case *ecdh.PublicKey:
+			pubBytes = key.Bytes()
+		}
+		switch key := priv.(type) {
+		case ed25519.PrivateKey:
+			privBytes = key.Seed()
+		case *ecdh.PrivateKey:
+			privBytes = key.Bytes()
+		}
+		var err error
+		if priv != nil {
+			result.Privkey, err = EncodeContent(privBytes, outputEncoding, EncodingHex)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result.Pubkey, err = EncodeContent(pubBytes, outputEncoding, EncodingHex)
+		return result, err
+	case "pem":
+		if priv != nil {
+			der, err := x509.MarshalPKCS8PrivateKey(priv)
+			if err != nil {
+				return nil, err
+			}
+			result.Privkey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		}
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		result.Pubkey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		return result, nil
+	case "openssh":
+		if _, ok := pub.(ed25519.PublicKey); !ok {
+			return nil, errors.New("openssh key format is ed25519 only")
+		}
+		if priv != nil {
+			block, err := ssh.MarshalPrivateKey(priv, "")
+			if err != nil {
+				return nil, err
+			}
+			result.Privkey = string(pem.EncodeToMemory(block))
+		}
+		sshPub, err := ssh.NewPublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		result.Pubkey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+		return result, nil
+	}
+	return nil, fmt.Errorf("invalid key format: %s, want raw, pem or openssh", format)
+}
+
+func isPEM(str string) bool {
+	return strings.HasPrefix(strings.TrimSpace(str), "-----BEGIN")
+}
+
+// ParseEd25519PrivateKey reads PKCS#8 or OpenSSH PEM, or a raw 32-byte seed or 64-byte key in encoding
+func ParseEd25519PrivateKey(str, encoding string) (ed25519.PrivateKey, error) {
+	if isPEM(str) {
+		key, err := ssh.ParseRawPrivateKey([]byte(strings.TrimSpace(str)))
+		if err != nil {
+			return nil, err
+		}
+		switch key := key.(type) {
+		case ed25519.PrivateKey:
+			return key, nil
+		case *ed25519.PrivateKey:
+			return *key, nil
+		}
+		return nil, fmt.Errorf("not an ed25519 private key: %T", key)
+	}
+	raw, err := DecodeContent("content", str, encoding, EncodingHex)
+	if err != nil {
+		return nil, err
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		priv := ed25519.NewKeyFromSeed(raw[:ed25519.SeedSize])
+		if !bytes.Equal(priv, raw) {
+			return nil, errors.New("invalid ed25519 privkey: public half doesn't match the seed")
+		}
+		return priv, nil
+	}
+	return nil, fmt.Errorf("invalid ed25519 privkey length: %d, want 32 or 64", len(raw))
+}
+
+// ParseEd25519PublicKey reads SPKI PEM, an OpenSSH authorized key line or raw hex
+func ParseEd25519PublicKey(str string) (ed25519.PublicKey, error) {
+	str = strings.TrimSpace(str)
+	if strings.HasPrefix(str, ssh.KeyAlgoED25519+" ") {
+		sshPub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(str))
+		if err != nil {
+			return nil, err
+		}
+		return sshPub.(ssh.CryptoPublicKey).CryptoPublicKey().(ed25519.PublicKey), nil
+	}
+	if isPEM(str) {
+		key, err := parseSPKI(str)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("not an ed25519 public key: %T", key)
+		}
+		return pub, nil
+	}
+	raw, err := decodeHex("pubkey", str)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 pubkey length: %d, want 32", len(raw))
+	}
+	return raw, nil
+}
+
+func parseSPKI(str string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(str)))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("invalid pem: want a PUBLIC KEY block")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// ed25519Options selects pure Ed25519, Ed25519ctx with a context or Ed25519ph, which signs SHA-512(message)
+func (ab *AsymmetricBody) ed25519Options() (*ed25519.Options, []byte, error) {
+	msg, err := DecodeContent("message", ab.Message, ab.MessageEncoding, EncodingUTF8)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := &ed25519.Options{Context: ab.Context}
+	if ab.Prehash {
+		digest := sha512.Sum512(msg)
+		opts.Hash, msg = crypto.SHA512, digest[:]
+	}
+	return opts, msg, nil
+}
+
+func Ed25519Content(ab *AsymmetricBody) (interface{}, error) {
+	switch ab.Operation {
+	case "generate":
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return FormatKeyPair(priv, pub, ab.KeyFormat, ab.OutputEncoding)
+	case "export":
+		if ab.Content == "" {
+			pub, err := ParseEd25519PublicKey(ab.Pubkey)
+			if err != nil {
+				return nil, err
+			}
+			return FormatKeyPair(nil, pub, ab.KeyFormat, ab.OutputEncoding)
+		}
+		priv, err := ParseEd25519PrivateKey(ab.Content, ab.InputEncoding)
+		if err != nil {
+			return nil, err
+		}
+		return FormatKeyPair(priv, priv.Public(), ab.KeyFormat, ab.OutputEncoding)
+	case "sign":
+		priv, err := ParseEd25519PrivateKey(ab.Content, ab.InputEncoding)
+		if err != nil {
+			return nil, err
+		}
+		opts, msg, err := ab.ed25519Options()
+		if err != nil {
+			return nil, err
+		}
+		sig, err := priv.Sign(nil, msg, opts)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeContent(sig, ab.OutputEncoding, EncodingHex)
+	case "verify":
+		pub, err := ParseEd25519PublicKey(ab.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := decodeHex("signature", ab.Signature)
+		if err != nil {
+			return nil, err
+		}
+		opts, msg, err := ab.ed25519Options()
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Valid bool `json:"valid"`
+		}
+		result.Valid = ed25519.VerifyWithOptions(pub, msg, sig, opts) == nil
+		return result, nil
+	}
+	return nil, fmt.Errorf("invalid asymmetric operation: %s", ab.Operation)
+}
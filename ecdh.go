@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var ecdhCurves = map[string]ecdh.Curve{
+	"x25519": ecdh.X25519(),
+	"p256":   ecdh.P256(),
+	"p384":   ecdh.P384(),
+}
+
+var ellipticCurves = map[ecdh.Curve]elliptic.Curve{
+	ecdh.P256(): elliptic.P256(),
+	ecdh.P384(): elliptic.P384(),
+}
+
+// ParseECDHPrivateKey reads PKCS#8 or SEC 1 PEM, or a raw scalar in encoding
+func ParseECDHPrivateKey(curve ecdh.Curve, str, encoding string) (*ecdh.PrivateKey, error) {
+	if !isPEM(str) {
+		raw, err := DecodeContent("content", str, encoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		return curve.NewPrivateKey(raw)
+	}
+	block, _ := pem.Decode([]byte(strings.TrimSpace(str)))
+	if block == nil {
+		return nil, errors.New("invalid pem private key")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("invalid pem: unexpected %s block", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var priv *ecdh.PrivateKey
+	switch key := key.(type) {
+	case *ecdh.PrivateKey:
+		priv = key
+	case *ecdsa.PrivateKey:
+		if priv, err = key.ECDH(); err != nil {
+			return nil, err
+		}
+	}
+	if priv == nil || priv.Curve() != curve {
+		return nil, fmt.Errorf("private key is not on %v", curve)
+	}
+	return priv, nil
+}
+
+// ParseECDHPublicKey reads SPKI PEM or raw hex, NIST keys may be compressed
+func ParseECDHPublicKey(curve ecdh.Curve, str string) (*ecdh.PublicKey, error) {
+	if isPEM(str) {
+		key, err := parseSPKI(str)
+		if err != nil {
+			return nil, err
+		}
+		var pub *ecdh.PublicKey
+		switch key := key.(type) {
+		case *ecdh.PublicKey:
+			pub = key
+		case *ecdsa.PublicKey:
+			if pub, err = key.ECDH(); err != nil {
+				return nil, err
+			}
+		}
+		if pub == nil || pub.Curve() != curve {
+			return nil, fmt.Errorf("public key is not on %v", curve)
+		}
+		return pub, nil
+	}
+	raw, err := decodeHex("pubkey", str)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := ellipticCurves[curve]; ok && len(raw) > 0 && (raw[0] == 0x02 || raw[0] == 0x03) {
+		x, y := elliptic.UnmarshalCompressed(c, raw)
+		if x == nil {
+			return nil, errors.New("invalid compressed pubkey")
+		}
+		raw = elliptic.Marshal(c, x, y)
+	}
+	return curve.NewPublicKey(raw)
+}
+
+// DeriveSharedKey runs the optional kdf over a shared secret: hkdf (hash, hex salt, utf8 info)
+// or sm2 (the GM/T 0003.4 KDF), key_len bytes long
+func DeriveSharedKey(ab *AsymmetricBody, shared []byte) ([]byte, error) {
+	keyLen := intOrDefault(ab.KeyLen, DefaultKdfKeyLen)
+	switch ab.Kdf {
+	case "":
+		if ab.KeyLen != 0 {
+			return nil, errors.New("key_len needs a kdf: hkdf or sm2")
+		}
+		return shared, nil
+	case "hkdf":
+		params := &kdfParams{hash: ab.Hash, keyLen: keyLen}
+		if params.hash == "" {
+			params.hash = "sha256"
+		}
+		if ab.Salt != "" {
+			salt, err := hex.DecodeString(ab.Salt)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex salt: %v", err)
+			}
+			params.salt = salt
+		}
+		return deriveKey("hkdf", shared, []byte(ab.Info), params)
+	case "sm2":
+		if keyLen <= 0 || keyLen > MaxKdfKeyLen {
+			return nil, fmt.Errorf("key_len must be in [1, %d]", MaxKdfKeyLen)
+		}
+		return SM2KDF(keyLen, shared), nil
+	}
+	return nil, fmt.Errorf("invalid kdf: %s, want hkdf or sm2", ab.Kdf)
+}
+
+// Secp256k1ECDH is the x coordinate of d*P
+func Secp256k1ECDH(priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey) ([]byte, error) {
+	x, y := crypto.S256().ScalarMult(pub.X, pub.Y, priv.D.FillBytes(make([]byte, 32)))
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errors.New("shared point is at infinity")
+	}
+	return x.FillBytes(make([]byte, 32)), nil
+}
+
+// ECDHContent serves x25519, p256 and p384
+func ECDHContent(curve ecdh.Curve, ab *AsymmetricBody) (interface{}, error) {
+	switch ab.Operation {
+	case "generate":
+		priv, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return FormatKeyPair(priv, priv.PublicKey(), ab.KeyFormat, ab.OutputEncoding)
+	case "export":
+		if ab.Content == "" {
+			pub, err := ParseECDHPublicKey(curve, ab.Pubkey)
+			if err != nil {
+				return nil, err
+			}
+			return FormatKeyPair(nil, pub, ab.KeyFormat, ab.OutputEncoding)
+		}
+		priv, err := ParseECDHPrivateKey(curve, ab.Content, ab.InputEncoding)
+		if err != nil {
+			return nil, err
+		}
+		return FormatKeyPair(priv, priv.PublicKey(), ab.KeyFormat, ab.OutputEncoding)
+	case "derive_shared":
+		priv, err := ParseECDHPrivateKey(curve, ab.Content, ab.InputEncoding)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := ParseECDHPublicKey(curve, ab.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := priv.ECDH(pub)
+		if err != nil {
+			return nil, err
+		}
+		key, err := DeriveSharedKey(ab, shared)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeContent(key, ab.OutputEncoding, EncodingHex)
+	}
+	return nil, fmt.Errorf("invalid asymmetric operation: %s", ab.Operation)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECDHKnownAnswer(t *testing.T) {
+	tests := []struct {
+		curve             ecdh.Curve
+		priv, pub, shared string
+	}{
+		// NIST CAVS KAS ECC CDH primitive, COUNT = 0
+		{ecdh.P256(), "7d7dc5f71eb29ddaf80d6214632eeae03d9058af1fb6d22ed80badb62bc1a534",
+			"04700c48f77f56584c5cc632ca65640db91b6bacce3a4df6b42ce7cc838833d287db71e509e3fd9b060ddb20ba5c51dcc5948d46fbf640dfe0441782cab85fa4ac",
+			"46fc62106420ff012e54a434fbdd2d25ccc5852060561e68040dd7778997bd7b"},
+		{ecdh.P384(), "3cc3122a68f0d95027ad38c067916ba0eb8c38894d22e1b15618b6818a661774ad463b205da88cf699ab4d43c9cf98a1",
+			"04a7c76b970c3b5fe8b05d2838ae04ab47697b9eaf52e764592efda27fe7513272734466b400091adbf2d68c58e0c50066" +
+				"ac68f19f2e1cb879aed43a9969b91a0839c4c38a49749b661efedf243451915ed0905a32b060992b468c64766fc8437a",
+			"5f9d29dc5e31a163060356213669c8ce132e22f57c9a04f40ba7fcead493b457e5621e766c40a2e3d4d6a04b25e533f1"},
+		// RFC 7748 §6.1, Alice with Bob's public key and Bob with Alice's
+		{ecdh.X25519(), "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a",
+			"de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f",
+			"4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742"},
+		{ecdh.X25519(), "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb",
+			"8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a",
+			"4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742"},
+	}
+	for _, test := range tests {
+		shared, err := ECDHContent(test.curve, &AsymmetricBody{Operation: "derive_shared", Content: test.priv, Pubkey: test.pub})
+		assert.Nil(t, err, test.curve)
+		assert.Equal(t, test.shared, shared, test.curve)
+	}
+
+	// the CAVS P-256 peer key compressed, y is even
+	shared, err := ECDHContent(ecdh.P256(), &AsymmetricBody{Operation: "derive_shared", Content: tests[0].priv,
+		Pubkey: "02700c48f77f56584c5cc632ca65640db91b6bacce3a4df6b42ce7cc838833d287"})
+	assert.Nil(t, err)
+	assert.Equal(t, tests[0].shared, shared)
+}
+
+// the x coordinate of the shared point, checked against a pure python secp256k1
+func TestSecp256k1ECDHKnownAnswer(t *testing.T) {
+	peerPub := "04a07bd2cc55724f05b7a54b6d9ac13547df10dfd13176725eb4f4122be547d3595d07a8ce778c52e1fa10fbe6e7d683792a7c7d55062be176288f3df1724a4b4a"
+	shared := "0108e5ab44136afad0dc0b8a6a0a0d9519d277a0211d3aa7e0df88a3ad1256a6"
+	result, err := Secp256k1Content(&AsymmetricBody{Operation: "derive_shared", Content: secp256k1TestKey, Pubkey: peerPub})
+	assert.Nil(t, err)
+	assert.Equal(t, shared, result)
+
+	// the peer derives the same secret from our key
+	result, err = Secp256k1Content(&AsymmetricBody{Operation: "derive_shared", Content: sm2TestKey,
+		Pubkey: "044e3b81af9c2234cad09d679ce6035ed1392347ce64ce405f5dcd36228a25de6e47fd35c4215d1edf53e6f83de344615ce719bdb0fd878f6ed76f06dd277956de"})
+	assert.Nil(t, err)
+	assert.Equal(t, shared, result)
+}
+
+func TestDeriveSharedKey(t *testing.T) {
+	// RFC 5869 A.1
+	ikm, _ := hex.DecodeString(strings.Repeat("0b", 22))
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	key, err := DeriveSharedKey(&AsymmetricBody{Kdf: "hkdf", Hash: "sha256", Salt: "000102030405060708090a0b0c", Info: string(info), KeyLen: 42}, ikm)
+	assert.Nil(t, err)
+	assert.Equal(t, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865", hex.EncodeToString(key))
+
+	// the hkdf path of derive_shared, sha256 and 32 bytes by default
+	x25519 := &AsymmetricBody{Operation: "derive_shared", Content: "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a",
+		Pubkey: "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f", Kdf: "hkdf", Info: "handshake"}
+	derived, err := ECDHContent(ecdh.X25519(), x25519)
+	assert.Nil(t, err)
+	shared, _ := hex.DecodeString("4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+	expected, err := deriveKey("hkdf", shared, []byte("handshake"), &kdfParams{hash: "sha256", keyLen: DefaultKdfKeyLen})
+	assert.Nil(t, err)
+	assert.Equal(t, hex.EncodeToString(expected), derived)
+
+	x25519.Kdf, x25519.KeyLen = "sm2", 16
+	derived, err = ECDHContent(ecdh.X25519(), x25519)
+	assert.Nil(t, err)
+	assert.Equal(t, hex.EncodeToString(SM2KDF(16, shared)), derived)
+
+	for _, test := range []struct {
+		ab  AsymmetricBody
+		err string
+	}{
+		{AsymmetricBody{KeyLen: 16}, "key_len needs a kdf: hkdf or sm2"},
+		{AsymmetricBody{Kdf: "pbkdf2"}, "invalid kdf: pbkdf2, want hkdf or sm2"},
+		{AsymmetricBody{Kdf: "hkdf", Salt: "zz"}, "invalid hex salt: encoding/hex: invalid byte: U+007A 'z'"},
+		{AsymmetricBody{Kdf: "hkdf", KeyLen: MaxKdfKeyLen + 1}, "key_len must be in [1, 1024]"},
+		{AsymmetricBody{Kdf: "sm2", KeyLen: -1}, "key_len must be in [1, 1024]"},
+	} {
+		_, err := DeriveSharedKey(&test.ab, shared)
+		assert.EqualError(t, err, test.err)
+	}
+}
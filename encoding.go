@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+)
+
+// input_encoding and output_encoding of HashBody, CodecBody and AsymmetricBody
+const (
+	EncodingUTF8      = "utf8"
+	EncodingHex       = "hex"
+	EncodingBase64    = "base64"
+	EncodingBase64URL = "base64url"
+	EncodingBase58    = "base58"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func DecodeBase58(content string) ([]byte, error) {
+	if i := strings.IndexFunc(content, func(r rune) bool {
+		return !strings.ContainsRune(base58Alphabet, r)
+	}); i >= 0 {
+		r, _ := utf8.DecodeRuneInString(content[i:])
+		return nil, fmt.Errorf("invalid base58 character %q at offset %d", r, i)
+	}
+	return base58.Decode(content), nil
+}
+
+// DecodeContent turns the request field name into bytes, encoding falls back to defaultEncoding when empty,
+// errors name the field so callers can return them as is
+func DecodeContent(name, content, encoding, defaultEncoding string) (data []byte, err error) {
+	if encoding == "" {
+		encoding = defaultEncoding
+	}
+	switch encoding {
+	case EncodingUTF8:
+		data = []byte(content)
+	case EncodingHex:
+		data, err = hex.DecodeString(content)
+	case EncodingBase64:
+		data, err = base64.StdEncoding.DecodeString(content)
+	case EncodingBase64URL:
+		data, err = base64.URLEncoding.DecodeString(content)
+	case EncodingBase58:
+		data, err = DecodeBase58(content)
+	default:
+		return nil, fmt.Errorf("invalid %s encoding: %s", name, encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %s: %v", encoding, name, err)
+	}
+	return
+}
+
+func EncodeContent(data []byte, encoding, defaultEncoding string) (string, error) {
+	if encoding == "" {
+		encoding = defaultEncoding
+	}
+	switch encoding {
+	case EncodingUTF8:
+		return string(data), nil
+	case EncodingHex:
+		return hex.EncodeToString(data), nil
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(data), nil
+	case EncodingBase64URL:
+		return base64.URLEncoding.EncodeToString(data), nil
+	case EncodingBase58:
+		return base58.Encode(data), nil
+	}
+	return "", fmt.Errorf("invalid output_encoding: %s", encoding)
+}
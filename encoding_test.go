@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeContentFieldName(t *testing.T) {
+	_, err := DecodeContent("key", "abc", "rot13", EncodingUTF8)
+	assert.EqualError(t, err, "invalid key encoding: rot13")
+
+	_, err = DecodeContent("key", "zz", EncodingHex, EncodingUTF8)
+	assert.ErrorContains(t, err, "invalid hex key:")
+
+	_, err = HashContent(&HashBody{Operation: "mac", Method: "sha256", Content: "abc", Key: "zz", KeyEncoding: EncodingHex})
+	assert.ErrorContains(t, err, "invalid hex key:")
+}
+
+func TestDecodeContentMacField(t *testing.T) {
+	mac, err := HashContent(&HashBody{Operation: "mac", Method: "sha256", Content: "abc", Key: "6b6579", KeyEncoding: EncodingHex})
+	assert.Nil(t, err)
+	valid, err := HashContent(&HashBody{Operation: "verify_mac", Method: "sha256", Content: "abc", Key: "key", Mac: mac.(string)})
+	assert.Nil(t, err)
+	assert.Equal(t, true, valid)
+
+	_, err = HashContent(&HashBody{Operation: "verify_mac", Method: "sha256", Content: "abc", Key: "key", Mac: "zz"})
+	assert.ErrorContains(t, err, "invalid hex mac:")
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/tjfoc/gmsm/sm3"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/ripemd160"
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/blake3"
+)
+
+var hashMethods = map[string]func() hash.Hash{
+	"md5":        md5.New,
+	"sha1":       sha1.New,
+	"sha224":     sha256.New224,
+	"sha256":     sha256.New,
+	"sha384":     sha512.New384,
+	"sha512":     sha512.New,
+	"sha512_256": sha512.New512_256,
+	"sha3_256":   sha3.New256,
+	"sha3_512":   sha3.New512,
+	//same as crypto.Keccak256 of go-ethereum
+	"keccak256": sha3.NewLegacyKeccak256,
+	"sm3":       sm3.New,
+	"ripemd160": ripemd160.New,
+	//RIPEMD160(SHA256(x)) of bitcoin addresses
+	"hash160": newHash160,
+	"blake2b": func() hash.Hash {
+		h, _ := blake2b.New512(nil)
+		return h
+	},
+	"blake2s": func() hash.Hash {
+		h, _ := blake2s.New256(nil)
+		return h
+	},
+	"blake3": func() hash.Hash {
+		return blake3.New(32, nil)
+	},
+}
+
+// MaxDigestSize caps the configurable output size of blake2b and blake3
+const MaxDigestSize = 1024
+
+type hash160 struct {
+	hash.Hash
+}
+
+func newHash160() hash.Hash {
+	return hash160{sha256.New()}
+}
+
+func (h hash160) Sum(b []byte) []byte {
+	r := ripemd160.New()
+	r.Write(h.Hash.Sum(nil))
+	return r.Sum(b)
+}
+
+func (h hash160) Size() int {
+	return ripemd160.Size
+}
+
+func NewHasher(method string) (hash.Hash, error) {
+	newHash, ok := hashMethods[method]
+	if !ok {
+		if _, ok = checksumMethods[method]; ok {
+			return nil, fmt.Errorf("%s is a checksum, not a secure digest, use operation checksum", method)
+		}
+		return nil, fmt.Errorf("invalid crypto method: %s", method)
+	}
+	return newHash(), nil
+}
+
+// NewHasherWithOptions supports an output size in bytes and a key for blake2b and blake3
+func NewHasherWithOptions(method string, size int, key []byte) (hash.Hash, error) {
+	if size == 0 && len(key) == 0 {
+		return NewHasher(method)
+	}
+	switch method {
+	case "blake2b":
+		if size == 0 {
+			size = blake2b.Size
+		}
+		if size < 1 || size > blake2b.Size {
+			return nil, fmt.Errorf("blake2b size must be in [1, %d]", blake2b.Size)
+		}
+		return blake2b.New(size, key)
+	case "blake3":
+		if size == 0 {
+			size = 32
+		}
+		if size < 1 || size > MaxDigestSize {
+			return nil, fmt.Errorf("blake3 size must be in [1, %d]", MaxDigestSize)
+		}
+		if len(key) != 0 && len(key) != 32 {
+			return nil, errors.New("blake3 key must be 32 bytes")
+		}
+		if len(key) == 0 {
+			key = nil
+		}
+		return blake3.New(size, key), nil
+	}
+	return nil, fmt.Errorf("hash method %s takes no size or key", method)
+}
+
+func HashSum(method string, data []byte) ([]byte, error) {
+	hasher, err := NewHasher(method)
+	if err != nil {
+		return nil, err
+	}
+	hasher.Write(data)
+	return hasher.Sum(nil), nil
+}
+
+func HMACSum(method string, key, data []byte) ([]byte, error) {
+	newHash, ok := hashMethods[method]
+	if !ok {
+		return nil, fmt.Errorf("invalid hmac method: %s", method)
+	}
+	m := hmac.New(newHash, key)
+	m.Write(data)
+	return m.Sum(nil), nil
+}
+
+func HashContent(hb *HashBody) (interface{}, error) {
+	content, err := DecodeContent("content", hb.Content, hb.InputEncoding, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+	key, err := DecodeContent("key", hb.Key, hb.KeyEncoding, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+	switch hb.Operation {
+	case "", "hash":
+		var digest []byte
+		if hb.Method == "sm3_with_id" {
+			pubBytes, err := decodeHex("pubkey", hb.Pubkey)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := ParseSM2PublicKey(pubBytes)
+			if err != nil {
+				return nil, err
+			}
+			digest, err = SM2IDDigest(pub, SM2UID(hb.Uid), content)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			hasher, err := NewHasherWithOptions(hb.Method, hb.Size, key)
+			if err != nil {
+				return nil, err
+			}
+			hasher.Write(content)
+			digest = hasher.Sum(nil)
+		}
+		return encodeSumLine(hb, digest)
+	case "checksum":
+		sum, err := ChecksumSum(hb.Method, content)
+		if err != nil {
+			return nil, err
+		}
+		return encodeSumLine(hb, sum)
+	case "mac":
+		mac, err := HMACSum(hb.Method, key, content)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeContent(mac, hb.OutputEncoding, EncodingHex)
+	case "verify_mac":
+		//the expected mac is in output_encoding
+		expected, err := DecodeContent("mac", hb.Mac, hb.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		mac, err := HMACSum(hb.Method, key, content)
+		if err != nil {
+			return nil, err
+		}
+		return hmac.Equal(mac, expected), nil
+	}
+	return nil, fmt.Errorf("invalid hash operation: %s", hb.Operation)
+}
+
+type StreamHashResult struct {
+	Digests map[string]string `json:"digests"`
+	Bytes   int64             `json:"bytes"`
+	Elapsed string            `json:"elapsed"`
+}
+
+// StreamHash feeds r into every requested hasher in one pass without buffering it
+func StreamHash(r io.Reader, methods []string, outputEncoding string) (*StreamHashResult, error) {
+	if len(methods) == 0 {
+		return nil, errors.New("no hash method")
+	}
+	hashers := make([]hash.Hash, len(methods))
+	writers := make([]io.Writer, len(methods))
+	for i, method := range methods {
+		hasher, err := NewHasher(method)
+		if err != nil {
+			return nil, err
+		}
+		hashers[i] = hasher
+		writers[i] = hasher
+	}
+	start := time.Now()
+	n, err := io.Copy(io.MultiWriter(writers...), r)
+	if err != nil {
+		return nil, err
+	}
+	result := &StreamHashResult{
+		Digests: make(map[string]string, len(methods)),
+		Bytes:   n,
+		Elapsed: time.Since(start).String(),
+	}
+	for i, method := range methods {
+		result.Digests[method], err = EncodeContent(hashers[i].Sum(nil), outputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func encodeSumLine(hb *HashBody, sum []byte) (string, error) {
+	encoded, err := EncodeContent(sum, hb.OutputEncoding, EncodingHex)
+	if err != nil {
+		return "", err
+	}
+	return FormatSumLine(hb.Format, hb.Method, len(sum), encoded, hb.Filename)
+}
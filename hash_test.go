@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSM3WithIDPubkeyPrefix(t *testing.T) {
+	pub := "046fd993301a0b380677fc89d6e8ad0ea4b5068e4764051c407f1a451e77d889d1298ed2d9c4ccfa56df693e3672839fe894ee7b5ef20836c18929c090c1e3e661"
+	plain, err := HashContent(&HashBody{Method: "sm3_with_id", Content: "abc", Pubkey: pub})
+	assert.Nil(t, err)
+	prefixed, err := HashContent(&HashBody{Method: "sm3_with_id", Content: "abc", Pubkey: "0x" + pub})
+	assert.Nil(t, err)
+	assert.Equal(t, plain, prefixed)
+
+	_, err = HashContent(&HashBody{Method: "sm3_with_id", Content: "abc", Pubkey: "0xzz"})
+	assert.NotNil(t, err)
+}
+
+// the GM/T 0003.5 signature example: key 3945208f…c5b8, default uid, message "message digest",
+// ZA = b2e14c5c79c6df5b85f4fe7ed8db7a262b9da7e07ccb0ea9f4747b8ccda8a4f3 and e = SM3(ZA || M)
+func TestSM3WithIDKnownAnswer(t *testing.T) {
+	pub := "0409f9df311e5421a150dd7d161e4bc5c672179fad1833fc076bb08ff356f35020" +
+		"ccea490ce26775a52dc6ea718cc1aa600aed05fbf35e084a6632f6072da9ad13"
+	digest, err := HashContent(&HashBody{Method: "sm3_with_id", Content: "message digest", Pubkey: pub})
+	assert.Nil(t, err)
+	assert.Equal(t, "f0b43e94ba45accaace692ed534382eb17e6ab5a19ce7b31f4486fdfc0d28640", digest)
+
+	withUID, err := HashContent(&HashBody{Method: "sm3_with_id", Content: "message digest", Pubkey: pub, Uid: "1234567812345678"})
+	assert.Nil(t, err)
+	assert.Equal(t, digest, withUID)
+	other, err := HashContent(&HashBody{Method: "sm3_with_id", Content: "message digest", Pubkey: pub, Uid: "ALICE123@YAHOO.COM"})
+	assert.Nil(t, err)
+	assert.NotEqual(t, digest, other)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// limits of one request, so a single call can not tie up the server
+const (
+	MaxPBKDF2Iterations = 2000000
+	MaxScryptN          = 1 << 20
+	MaxScryptMemory     = 256 << 20 //128*N*r bytes
+	MaxScryptP          = 16
+	MaxArgon2Memory     = 256 << 10 //KiB
+	MaxArgon2Time       = 10
+	MaxArgon2Threads    = 8
+	MaxBcryptCost       = 14
+	MaxKdfKeyLen        = 1024
+	DefaultKdfSaltLen   = 16
+	DefaultKdfKeyLen    = 32
+)
+
+var kdfHashes = map[string]func() hash.Hash{
+	"sha256": hashMethods["sha256"],
+	"sha512": hashMethods["sha512"],
+	"sm3":    hashMethods["sm3"],
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+type kdfParams struct {
+	hash       string
+	iterations int
+	n, r, p    int
+	memory     int
+	time       int
+	threads    int
+	keyLen     int
+	salt       []byte
+}
+
+type KdfResult struct {
+	Encoded string `json:"encoded,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Salt    string `json:"salt,omitempty"`
+}
+
+func KdfContent(kb *KdfBody) (interface{}, error) {
+	password, err := DecodeContent("content", kb.Content, kb.InputEncoding, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+	switch kb.Operation {
+	case "derive":
+		return kdfDerive(kb, password)
+	case "verify":
+		return kdfVerify(kb, password)
+	}
+	return nil, fmt.Errorf("invalid kdf operation: %s", kb.Operation)
+}
+
+func kdfDerive(kb *KdfBody, password []byte) (interface{}, error) {
+	if kb.Method == "bcrypt" {
+		cost := intOrDefault(kb.Cost, bcrypt.DefaultCost)
+		if cost < bcrypt.MinCost || cost > MaxBcryptCost {
+			return nil, fmt.Errorf("bcrypt cost must be in [%d, %d]", bcrypt.MinCost, MaxBcryptCost)
+		}
+		encoded, err := bcrypt.GenerateFromPassword(password, cost)
+		if err != nil {
+			return nil, err
+		}
+		return &KdfResult{Encoded: string(encoded)}, nil
+	}
+	params := &kdfParams{
+		hash:       kb.Hash,
+		iterations: intOrDefault(kb.Iterations, 600000),
+		n:          intOrDefault(kb.N, 1<<15),
+		r:          intOrDefault(kb.R, 8),
+		p:          intOrDefault(kb.P, 1),
+		memory:     intOrDefault(kb.Memory, 64<<10),
+		time:       intOrDefault(kb.Time, 3),
+		threads:    intOrDefault(kb.Threads, 4),
+		keyLen:     intOrDefault(kb.KeyLen, DefaultKdfKeyLen),
+	}
+	if params.hash == "" {
+		params.hash = "sha256"
+	}
+	var err error
+	if kb.Salt != "" {
+		params.salt, err = hex.DecodeString(kb.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex salt: %v", err)
+		}
+	} else if kb.Method != "hkdf" {
+		params.salt = make([]byte, DefaultKdfSaltLen)
+		if _, err = io.ReadFull(rand.Reader, params.salt); err != nil {
+			return nil, err
+		}
+	}
+	key, err := deriveKey(kb.Method, password, []byte(kb.Info), params)
+	if err != nil {
+		return nil, err
+	}
+	result := &KdfResult{Salt: hex.EncodeToString(params.salt)}
+	result.Key, err = EncodeContent(key, kb.OutputEncoding, EncodingHex)
+	if err != nil {
+		return nil, err
+	}
+	if kb.Method != "hkdf" {
+		result.Encoded = formatPHC(kb.Method, params, key)
+	}
+	return result, nil
+}
+
+func kdfVerify(kb *KdfBody, password []byte) (interface{}, error) {
+	switch kb.Method {
+	case "bcrypt":
+		cost, err := bcrypt.Cost([]byte(kb.Encoded))
+		if err != nil {
+			return nil, err
+		}
+		if cost > MaxBcryptCost {
+			return nil, fmt.Errorf("bcrypt cost %d exceeds %d", cost, MaxBcryptCost)
+		}
+		return bcrypt.CompareHashAndPassword([]byte(kb.Encoded), password) == nil, nil
+	case "hkdf":
+		//encoded is the expected key in output_encoding
+		expected, err := DecodeContent("encoded", kb.Encoded, kb.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		salt, err := hex.DecodeString(kb.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex salt: %v", err)
+		}
+		params := &kdfParams{hash: kb.Hash, keyLen: len(expected), salt: salt}
+		if params.hash == "" {
+			params.hash = "sha256"
+		}
+		key, err := deriveKey(kb.Method, password, []byte(kb.Info), params)
+		if err != nil {
+			return nil, err
+		}
+		return subtle.ConstantTimeCompare(key, expected) == 1, nil
+	}
+	method, params, expected, err := parsePHC(kb.Encoded)
+	if err != nil {
+		return nil, err
+	}
+	if method != kb.Method {
+		return nil, fmt.Errorf("encoded hash is %s, not %s", method, kb.Method)
+	}
+	key, err := deriveKey(method, password, nil, params)
+	if err != nil {
+		return nil, err
+	}
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+func deriveKey(method string, password, info []byte, params *kdfParams) ([]byte, error) {
+	if params.keyLen <= 0 || params.keyLen > MaxKdfKeyLen {
+		return nil, fmt.Errorf("key_len must be in [1, %d]", MaxKdfKeyLen)
+	}
+	switch method {
+	case "pbkdf2":
+		newHash, ok := kdfHashes[params.hash]
+		if !ok {
+			return nil, fmt.Errorf("invalid pbkdf2 hash: %s", params.hash)
+		}
+		if params.iterations <= 0 || params.iterations > MaxPBKDF2Iterations {
+			return nil, fmt.Errorf("pbkdf2 iterations must be in [1, %d]", MaxPBKDF2Iterations)
+		}
+		return pbkdf2.Key(password, params.salt, params.iterations, params.keyLen, newHash), nil
+	case "scrypt":
+		if params.n <= 1 || params.n > MaxScryptN || params.n&(params.n-1) != 0 {
+			return nil, fmt.Errorf("scrypt n must be a power of 2 in [2, %d]", MaxScryptN)
+		}
+		if params.r <= 0 || params.p <= 0 || params.p > MaxScryptP || 128*params.n*params.r > MaxScryptMemory {
+			return nil, fmt.Errorf("scrypt r and p must be positive, p <= %d and 128*n*r <= %d", MaxScryptP, MaxScryptMemory)
+		}
+		return scrypt.Key(password, params.salt, params.n, params.r, params.p, params.keyLen)
+	case "argon2id":
+		if params.time <= 0 || params.time > MaxArgon2Time {
+			return nil, fmt.Errorf("argon2id time must be in [1, %d]", MaxArgon2Time)
+		}
+		if params.threads <= 0 || params.threads > MaxArgon2Threads {
+			return nil, fmt.Errorf("argon2id threads must be in [1, %d]", MaxArgon2Threads)
+		}
+		if params.memory < 8*params.threads || params.memory > MaxArgon2Memory {
+			return nil, fmt.Errorf("argon2id memory must be in [%d, %d] KiB", 8*params.threads, MaxArgon2Memory)
+		}
+		return argon2.IDKey(password, params.salt, uint32(params.time), uint32(params.memory), uint8(params.threads), uint32(params.keyLen)), nil
+	case "hkdf":
+		newHash, ok := kdfHashes[params.hash]
+		if !ok {
+			return nil, fmt.Errorf("invalid hkdf hash: %s", params.hash)
+		}
+		if params.keyLen > 255*newHash().Size() {
+			return nil, fmt.Errorf("hkdf key_len must be <= %d", 255*newHash().Size())
+		}
+		key := make([]byte, params.keyLen)
+		if _, err := io.ReadFull(hkdf.New(newHash, password, params.salt, info), key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("invalid kdf method: %s", method)
+}
+
+// formatPHC encodes the parameters, salt and key as a PHC string, e.g. $argon2id$v=19$m=65536,t=3,p=4$salt$hash
+func formatPHC(method string, params *kdfParams, key []byte) string {
+	var id, fields string
+	switch method {
+	case "pbkdf2":
+		id = "pbkdf2-" + params.hash
+		fields = fmt.Sprintf("i=%d", params.iterations)
+	case "scrypt":
+		id = "scrypt"
+		ln := 0
+		for n := params.n; n > 1; n >>= 1 {
+			ln++
+		}
+		fields = fmt.Sprintf("ln=%d,r=%d,p=%d", ln, params.r, params.p)
+	case "argon2id":
+		id = "argon2id"
+		fields = fmt.Sprintf("v=%d$m=%d,t=%d,p=%d", argon2.Version, params.memory, params.time, params.threads)
+	}
+	return fmt.Sprintf("$%s$%s$%s$%s", id, fields, phcEncoding.EncodeToString(params.salt), phcEncoding.EncodeToString(key))
+}
+
+func parsePHC(encoded string) (method string, params *kdfParams, key []byte, err error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) < 5 || fields[0] != "" {
+		return "", nil, nil, errors.New("invalid PHC string: want $id[$v=version]$params$salt$hash")
+	}
+	id := fields[1]
+	fields = fields[2:]
+	if strings.HasPrefix(fields[0], "v=") {
+		if fields[0] != fmt.Sprintf("v=%d", argon2.Version) {
+			return "", nil, nil, fmt.Errorf("unsupported PHC version: %s", fields[0])
+		}
+		fields = fields[1:]
+	}
+	if len(fields) != 3 {
+		return "", nil, nil, errors.New("invalid PHC string: want $id[$v=version]$params$salt$hash")
+	}
+	params = &kdfParams{}
+	if params.salt, err = phcEncoding.DecodeString(fields[1]); err != nil {
+		return "", nil, nil, fmt.Errorf("invalid PHC salt: %v", err)
+	}
+	if key, err = phcEncoding.DecodeString(fields[2]); err != nil {
+		return "", nil, nil, fmt.Errorf("invalid PHC hash: %v", err)
+	}
+	params.keyLen = len(key)
+	values := make(map[string]int)
+	for _, kv := range strings.Split(fields[0], ",") {
+		pair := strings.SplitN(kv, "=", 2)
+		if len(pair) != 2 {
+			return "", nil, nil, fmt.Errorf("invalid PHC parameter: %s", kv)
+		}
+		values[pair[0]], err = strconv.Atoi(pair[1])
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("invalid PHC parameter: %s", kv)
+		}
+	}
+	switch {
+	case strings.HasPrefix(id, "pbkdf2-"):
+		method = "pbkdf2"
+		params.hash = strings.TrimPrefix(id, "pbkdf2-")
+		params.iterations = values["i"]
+	case id == "scrypt":
+		method = "scrypt"
+		if values["ln"] <= 0 || values["ln"] >= 31 {
+			return "", nil, nil, fmt.Errorf("invalid scrypt ln: %d", values["ln"])
+		}
+		params.n = 1 << uint(values["ln"])
+		params.r = values["r"]
+		params.p = values["p"]
+	case id == "argon2id":
+		method = "argon2id"
+		params.memory = values["m"]
+		params.time = values["t"]
+		params.threads = values["p"]
+	default:
+		return "", nil, nil, fmt.Errorf("unsupported PHC id: %s", id)
+	}
+	return method, params, key, nil
+}
+
+func intOrDefault(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
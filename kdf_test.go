@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func kdfDeriveKey(t *testing.T, kb *KdfBody) *KdfResult {
+	kb.Operation = "derive"
+	result, err := KdfContent(kb)
+	assert.Nil(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+	return result.(*KdfResult)
+}
+
+// PBKDF2-HMAC-SHA256 and scrypt vectors are from RFC 7914 section 11 and 12
+func TestKdfRFC7914(t *testing.T) {
+	tests := []struct {
+		kb  KdfBody
+		key string
+	}{
+		{KdfBody{Method: "pbkdf2", Content: "passwd", Salt: hex.EncodeToString([]byte("salt")), Iterations: 1, KeyLen: 64},
+			"55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{KdfBody{Method: "pbkdf2", Content: "Password", Salt: hex.EncodeToString([]byte("NaCl")), Iterations: 80000, KeyLen: 64},
+			"4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+		{KdfBody{Method: "scrypt", Content: "password", Salt: hex.EncodeToString([]byte("NaCl")), N: 1024, R: 8, P: 16, KeyLen: 64},
+			"fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+		{KdfBody{Method: "scrypt", Content: "pleaseletmein", Salt: hex.EncodeToString([]byte("SodiumChloride")), N: 16384, R: 8, P: 1, KeyLen: 64},
+			"7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887"},
+	}
+	for _, test := range tests {
+		result := kdfDeriveKey(t, &test.kb)
+		assert.Equal(t, test.key, result.Key, test.kb.Method)
+	}
+}
+
+// RFC 9106 section 5.3 also feeds a secret and associated data, which golang.org/x/crypto/argon2 doesn't expose,
+// so the argon2id vectors come from the test suite of the reference implementation the RFC points to
+func TestKdfArgon2id(t *testing.T) {
+	tests := []struct {
+		kb      KdfBody
+		encoded string
+	}{
+		{KdfBody{Method: "argon2id", Content: "password", Salt: hex.EncodeToString([]byte("somesalt")), Time: 2, Memory: 1 << 16, Threads: 1},
+			"$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"},
+		{KdfBody{Method: "argon2id", Content: "password", Salt: hex.EncodeToString([]byte("somesalt")), Time: 2, Memory: 1 << 8, Threads: 2},
+			"$argon2id$v=19$m=256,t=2,p=2$c29tZXNhbHQ$bQk8UB/VmZZF4Oo79iDXuL5/0ttZwg2f/5U52iv1cDc"},
+	}
+	for _, test := range tests {
+		result := kdfDeriveKey(t, &test.kb)
+		assert.Equal(t, test.encoded, result.Encoded)
+	}
+}
+
+func TestKdfPHCRoundTrip(t *testing.T) {
+	tests := []KdfBody{
+		{Method: "pbkdf2", Iterations: 1000},
+		{Method: "pbkdf2", Hash: "sha512", Iterations: 1000, KeyLen: 64},
+		{Method: "pbkdf2", Hash: "sm3", Iterations: 1000},
+		{Method: "scrypt", N: 1 << 10, R: 8, P: 2},
+		{Method: "argon2id", Memory: 1 << 10, Time: 1, Threads: 2, KeyLen: 20},
+	}
+	for _, kb := range tests {
+		kb.Content = "correct horse battery staple"
+		result := kdfDeriveKey(t, &kb)
+		prefix := "$" + kb.Method
+		if kb.Method == "pbkdf2" {
+			prefix += "-" + kb.Hash
+			if kb.Hash == "" {
+				prefix += "sha256"
+			}
+		}
+		assert.True(t, strings.HasPrefix(result.Encoded, prefix+"$"), result.Encoded)
+
+		method, params, key, err := parsePHC(result.Encoded)
+		assert.Nil(t, err)
+		assert.Equal(t, kb.Method, method)
+		assert.Equal(t, result.Key, hex.EncodeToString(key))
+		assert.Equal(t, result.Salt, hex.EncodeToString(params.salt))
+
+		for password, valid := range map[string]bool{kb.Content: true, "wrong": false} {
+			ok, err := KdfContent(&KdfBody{Method: kb.Method, Operation: "verify", Content: password, Encoded: result.Encoded})
+			assert.Nil(t, err)
+			assert.Equal(t, valid, ok, kb.Method)
+		}
+	}
+	ok, err := KdfContent(&KdfBody{Method: "scrypt", Operation: "verify", Encoded: "$argon2id$v=19$m=256,t=2,p=2$c29tZXNhbHQ$bQk8UB/VmZZF4Oo79iDXuL5/0ttZwg2f/5U52iv1cDc"})
+	assert.NotNil(t, err)
+	assert.Nil(t, ok)
+}
+
+func TestKdfLimits(t *testing.T) {
+	salt := hex.EncodeToString([]byte("salt"))
+	tests := []KdfBody{
+		{Method: "pbkdf2", Iterations: MaxPBKDF2Iterations + 1},
+		{Method: "pbkdf2", Iterations: -1},
+		{Method: "pbkdf2", Hash: "md5"},
+		{Method: "scrypt", N: MaxScryptN << 1},
+		{Method: "scrypt", N: 1000},
+		{Method: "scrypt", N: 1 << 20, R: 8},
+		{Method: "scrypt", P: MaxScryptP + 1},
+		{Method: "argon2id", Memory: MaxArgon2Memory + 1},
+		{Method: "argon2id", Memory: 8, Threads: 2},
+		{Method: "argon2id", Time: MaxArgon2Time + 1},
+		{Method: "argon2id", Threads: MaxArgon2Threads + 1},
+		{Method: "bcrypt", Cost: MaxBcryptCost + 1},
+		{Method: "hkdf", KeyLen: 255*32 + 1},
+		{Method: "hkdf", KeyLen: MaxKdfKeyLen + 1},
+		{Method: "scrypt", KeyLen: -1},
+	}
+	for _, kb := range tests {
+		kb.Operation, kb.Content, kb.Salt = "derive", "password", salt
+		_, err := KdfContent(&kb)
+		assert.NotNil(t, err, "%+v", kb)
+	}
+
+	// verify reads the parameters from the encoded string, so they are bounded the same way
+	encoded := []string{
+		"$pbkdf2-sha256$i=2000001$c2FsdA$AAAA",
+		"$scrypt$ln=21,r=8,p=1$c2FsdA$AAAA",
+		"$scrypt$ln=10,r=8,p=17$c2FsdA$AAAA",
+		"$argon2id$v=19$m=262145,t=1,p=1$c2FsdA$AAAA",
+		"$argon2id$v=19$m=1024,t=11,p=1$c2FsdA$AAAA",
+		"$argon2id$v=19$m=1024,t=1,p=9$c2FsdA$AAAA",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$AAAA",
+	}
+	for _, e := range encoded {
+		method := strings.Split(e, "$")[1]
+		method = strings.TrimSuffix(method, "-sha256")
+		_, err := KdfContent(&KdfBody{Method: method, Operation: "verify", Content: "password", Encoded: e})
+		assert.NotNil(t, err, e)
+	}
+	_, err := KdfContent(&KdfBody{Method: "bcrypt", Operation: "verify", Content: "password",
+		Encoded: "$2a$15$abcdefghijklmnopqrstuu5s2v8.iXieOjg/.AySBTTZIIVFJeBui"})
+	assert.EqualError(t, err, "bcrypt cost 15 exceeds 14")
+}
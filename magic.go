@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultMagicDepth = 3
+	MaxMagicDepth     = 6
+	DefaultMagicLimit = 5
+	MaxMagicLimit     = 50
+	// MaxMagicNodes and MaxMagicBytes bound the search, every decompression may return MaxDecompressedSize
+	MaxMagicNodes = 1000
+	MaxMagicBytes = 4 * MaxDecompressedSize
+)
+
+// MagicStep is a codec request that replays one decoding step on /crypto/codec
+type MagicStep struct {
+	Method    string `json:"method"`
+	Radix     int    `json:"radix,omitempty"`
+	Separator string `json:"separator,omitempty"`
+}
+
+type MagicChain struct {
+	Steps  []MagicStep `json:"steps"`
+	Score  float64     `json:"score"`
+	Length int         `json:"length"`
+	Text   string      `json:"text,omitempty"`
+	Data   string      `json:"data"`
+}
+
+type magicDecoder struct {
+	step MagicStep
+	// text decoders only run on printable ASCII
+	text   bool
+	decode func(data []byte) ([]byte, error)
+}
+
+var magicDecoders = buildMagicDecoders()
+
+func buildMagicDecoders() []magicDecoder {
+	var decoders []magicDecoder
+	for _, radix := range []int{2, 8, 10} {
+		for _, sep := range []string{"", " ", ","} {
+			opts := RadixOptions{Radix: radix, Separator: sep}
+			decoders = append(decoders, magicDecoder{
+				step: MagicStep{Method: "radix_decode", Radix: radix, Separator: sep},
+				text: true,
+				decode: func(data []byte) ([]byte, error) {
+					if opts.Separator != "" && !strings.Contains(string(data), opts.Separator) {
+						return nil, errors.New("no separator")
+					}
+					return DecodeRadix(string(data), opts)
+				},
+			})
+		}
+	}
+	for _, name := range append(autoDecodeOrder, "base64mime") {
+		codec := textCodecs[name]
+		decoders = append(decoders, magicDecoder{
+			step: MagicStep{Method: name + "_decode"},
+			text: true,
+			decode: func(data []byte) ([]byte, error) {
+				return codec.Decode(strings.TrimSpace(string(data)))
+			},
+		})
+	}
+	for _, name := range []string{"gzip", "zlib", "zstd", "deflate", "snappy"} {
+		name := name
+		decoders = append(decoders, magicDecoder{
+			step: MagicStep{Method: name + "_decompress"},
+			decode: func(data []byte) ([]byte, error) {
+				return Decompress(name, data)
+			},
+		})
+	}
+	return decoders
+}
+
+func isPrintableASCII(data []byte) bool {
+	for _, b := range data {
+		if (b < 0x20 || b > 0x7e) && b != '\n' && b != '\r' && b != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// MagicScore ranks data by how much it looks like plain text: the printable share,
+// a bonus for spaces between words, scaled down by Shannon entropy and halved for bare digit strings
+func MagicScore(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var printable, spaces, total int
+	if utf8.Valid(data) {
+		for _, r := range string(data) {
+			total++
+			if unicode.IsPrint(r) || r == '\n' || r == '\r' || r == '\t' {
+				printable++
+			}
+			if unicode.IsSpace(r) {
+				spaces++
+			}
+		}
+	} else {
+		for _, b := range data {
+			total++
+			if b >= 0x20 && b <= 0x7e {
+				printable++
+			}
+			if b == ' ' || b == '\n' {
+				spaces++
+			}
+		}
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	var entropy float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(len(data))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	score := float64(printable) / float64(total)
+	score *= 0.6 + 0.4*math.Min(float64(spaces)/float64(total)/0.15, 1)
+	score *= 1 - entropy/16
+	if strings.Trim(string(data), "0123456789abcdefABCDEF \r\n\t,") == "" {
+		score /= 2
+	}
+	return math.Round(score*1e4) / 1e4
+}
+
+type magicNode struct {
+	data  []byte
+	steps []MagicStep
+	score float64
+}
+
+// Magic searches decoder chains breadth first, so every distinct output keeps its shortest chain
+func Magic(mb *MagicBody) ([]MagicChain, error) {
+	depth := intOrDefault(mb.Depth, DefaultMagicDepth)
+	if depth < 1 || depth > MaxMagicDepth {
+		return nil, fmt.Errorf("invalid depth: %d, want 1..%d", depth, MaxMagicDepth)
+	}
+	limit := intOrDefault(mb.Limit, DefaultMagicLimit)
+	if limit < 1 || limit > MaxMagicLimit {
+		return nil, fmt.Errorf("invalid limit: %d, want 1..%d", limit, MaxMagicLimit)
+	}
+	content, err := DecodeContent("content", mb.Content, mb.InputEncoding, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+	found := magicSearch(content, depth, MaxMagicNodes, MaxMagicBytes)
+	if len(found) == 0 {
+		return nil, errors.New("no decoder matches content")
+	}
+	for i := range found {
+		found[i].score = MagicScore(found[i].data)
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].score != found[j].score {
+			return found[i].score > found[j].score
+		}
+		return len(found[i].steps) < len(found[j].steps)
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	chains := make([]MagicChain, len(found))
+	for i, node := range found {
+		chains[i] = MagicChain{Steps: node.steps, Score: node.score, Length: len(node.data)}
+		if utf8.Valid(node.data) {
+			chains[i].Text = string(node.data)
+		}
+		chains[i].Data, err = EncodeContent(node.data, mb.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return chains, nil
+}
+
+// magicSearch stops once maxNodes outputs are found or maxBytes have been decoded
+func magicSearch(content []byte, depth, maxNodes, maxBytes int) []magicNode {
+	seen := map[string]bool{string(content): true}
+	level := []magicNode{{data: content}}
+	var found []magicNode
+	budget := maxBytes
+search:
+	for d := 0; d < depth; d++ {
+		var next []magicNode
+		for _, node := range level {
+			text := isPrintableASCII(node.data)
+			for _, decoder := range magicDecoders {
+				if decoder.text && !text {
+					continue
+				}
+				decoded, err := decoder.decode(node.data)
+				if err != nil || len(decoded) == 0 || seen[string(decoded)] {
+					continue
+				}
+				seen[string(decoded)] = true
+				steps := append(append([]MagicStep{}, node.steps...), decoder.step)
+				next = append(next, magicNode{data: decoded, steps: steps})
+				budget -= len(decoded)
+				if len(found)+len(next) >= maxNodes || budget < 0 {
+					found = append(found, next...)
+					break search
+				}
+			}
+		}
+		found = append(found, next...)
+		level = next
+	}
+	return found
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the program the original ascii.go Decrypt carried as 8-bit binary digits
+const asciiBlobText = "\npackage main\n\nimport \"fmt\"\n\n//M6Ly9naXRodWIuY29tL2RhcHBsZWRnZXIvQW5uQ2hhaW4vaXNzdWVzLzM3aHR0cH\nfunc main() {\n\tfmt.Println(\"Clockwise rotate 6\")\n}\n"
+
+func TestMagicAsciiBlob(t *testing.T) {
+	chains, err := Magic(&MagicBody{Content: Encrypt(asciiBlobText)})
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultMagicLimit, len(chains))
+	assert.Equal(t, []MagicStep{{Method: "radix_decode", Radix: 2}}, chains[0].Steps)
+	assert.Equal(t, asciiBlobText, chains[0].Text)
+	assert.Equal(t, len(asciiBlobText), chains[0].Length)
+	for i := 1; i < len(chains); i++ {
+		assert.GreaterOrEqual(t, chains[i-1].Score, chains[i].Score)
+	}
+
+	//the comment rotated by 6, as binary digits of its base64
+	url := "https://github.com/dappledger/AnnChain/issues/37"
+	content := Encrypt(base64.StdEncoding.EncodeToString([]byte(url)))
+	chains, err = Magic(&MagicBody{Content: content, Limit: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []MagicChain{{
+		Steps:  []MagicStep{{Method: "radix_decode", Radix: 2}, {Method: "base64std_decode"}},
+		Score:  MagicScore([]byte(url)),
+		Length: len(url),
+		Text:   url,
+		Data:   hex.EncodeToString([]byte(url)),
+	}}, chains)
+
+	//one step only reaches the base64
+	chains, err = Magic(&MagicBody{Content: content, Depth: 1, Limit: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []MagicStep{{Method: "radix_decode", Radix: 2}}, chains[0].Steps)
+}
+
+func TestMagicLimits(t *testing.T) {
+	for _, test := range []struct {
+		depth, limit int
+		err          string
+	}{
+		{MaxMagicDepth + 1, 0, "invalid depth: 7, want 1..6"},
+		{-1, 0, "invalid depth: -1, want 1..6"},
+		{0, MaxMagicLimit + 1, "invalid limit: 51, want 1..50"},
+		{0, -1, "invalid limit: -1, want 1..50"},
+	} {
+		_, err := Magic(&MagicBody{Content: "68656c6c6f", Depth: test.depth, Limit: test.limit})
+		assert.EqualError(t, err, test.err)
+	}
+
+	chains, err := Magic(&MagicBody{Content: "68656c6c6f", Depth: MaxMagicDepth, Limit: MaxMagicLimit})
+	assert.Nil(t, err)
+	assert.LessOrEqual(t, len(chains), MaxMagicLimit)
+
+	_, err = Magic(&MagicBody{Content: "!!!"})
+	assert.EqualError(t, err, "no decoder matches content")
+}
+
+func TestMagicSearchBudget(t *testing.T) {
+	content := []byte(Encrypt(asciiBlobText))
+	all := magicSearch(content, DefaultMagicDepth, MaxMagicNodes, MaxMagicBytes)
+	assert.Greater(t, len(all), 3)
+
+	//the node cap keeps the first nodes of the breadth first order
+	capped := magicSearch(content, DefaultMagicDepth, 3, MaxMagicBytes)
+	assert.Equal(t, all[:3], capped)
+
+	//the first decoding alone is over the byte budget
+	capped = magicSearch(content, DefaultMagicDepth, MaxMagicNodes, len(asciiBlobText)-1)
+	assert.Equal(t, 1, len(capped))
+	assert.Equal(t, asciiBlobText, string(capped[0].data))
+
+	capped = magicSearch(content, DefaultMagicDepth, MaxMagicNodes, len(asciiBlobText))
+	assert.Greater(t, len(capped), 1)
+}
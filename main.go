@@ -1,16 +1,16 @@
 package main
 
 import (
-	"crypto/md5"
-	"encoding/base64"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"os"
+	"strings"
 
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/urfave/cli"
 )
 
 type HttpResult struct {
@@ -29,35 +29,202 @@ func ErrorResponse(w http.ResponseWriter, errCode int, err error) {
 
 func ResultResponse(w http.ResponseWriter, result []byte) {
 	fmt.Println("resp:   ", string(result))
+	writeResult(w, result)
+}
+
+func writeResult(w http.ResponseWriter, result []byte) {
 	w.WriteHeader(http.StatusOK)
 	w.Write(result)
 }
 
+// shared by HashBody, CodecBody and AsymmetricBody: utf8, hex, base64, base64url or base58
+type ContentEncoding struct {
+	InputEncoding  string `json:"input_encoding"`
+	OutputEncoding string `json:"output_encoding"`
+}
+
 type HashBody struct {
 	Method  string `json:"method"`
 	Content string `json:"content"`
+	ContentEncoding
+	//hash(default), checksum, mac or verify_mac
+	Operation string `json:"operation"`
+	//hash and checksum: gnu (sha256sum style) or bsd (tagged) line of filename, "-" by default
+	Format   string `json:"format"`
+	Filename string `json:"filename"`
+	//hmac key, or blake2b/blake3 key of hash, utf8 by default
+	Key         string `json:"key"`
+	KeyEncoding string `json:"key_encoding"`
+	//blake2b/blake3 output size in bytes
+	Size int `json:"size"`
+	//the expected mac of verify_mac, in output_encoding
+	Mac string `json:"mac"`
+	//sm3_with_id only, default uid is 1234567812345678
+	Pubkey string `json:"pubkey"`
+	Uid    string `json:"uid"`
 }
 
 type CodecBody struct {
 	Method  string `json:"method"`
 	Content string `json:"content"`
+	ContentEncoding
+	//base58check version byte
+	Version int `json:"version"`
+	//bech32/bech32m, segwit prefixes the data with witness_version
+	Hrp            string `json:"hrp"`
+	Segwit         bool   `json:"segwit"`
+	WitnessVersion int    `json:"witness_version"`
+	//rlp_encode: nested array of hex strings, content is parsed as JSON when empty
+	Value json.RawMessage `json:"value"`
+	//protobuf_decode: base64 FileDescriptorSet (protoc --descriptor_set_out) and the full message name
+	DescriptorSet string `json:"descriptor_set"`
+	MessageType   string `json:"message_type"`
+	//radix_encode/radix_decode, see RadixOptions
+	Radix     int    `json:"radix"`
+	Bits      int    `json:"bits"`
+	Mode      string `json:"mode"`
+	Separator string `json:"separator"`
+	//<method>_compress level, 0 is the method default
+	Level int `json:"level"`
 }
 
 type AsymmetricBody struct {
 	Method    string `json:"method"`
 	Operation string `json:"operation"`
-	Content   string `json:"content"`
+	//private key, hex by default, ed25519/x25519/p256/p384 also take pem
+	Content string `json:"content"`
+	ContentEncoding
+	//sign, verify and recover: a digest (hex by default), or a message (utf8 by default) hashed with hash,
+	//sm2 always signs SM3(Z||message)
+	Message         string `json:"message"`
+	MessageEncoding string `json:"message_encoding"`
+	Hash            string `json:"hash"`
+	//verify and recover, hex of rsv, der or compact (sm2: raw or der), detected when signature_format is empty
+	Signature       string `json:"signature"`
+	SignatureFormat string `json:"signature_format"`
+	//verify, sm2 encrypt and the peer of derive_shared, hex (ed25519/x25519/p256/p384 also take pem)
+	Pubkey string `json:"pubkey"`
+	//sm2 user id of the Z value, default uid is 1234567812345678
+	Uid string `json:"uid"`
+	//sm2 decrypt and convert, hex
+	Ciphertext string `json:"ciphertext"`
+	//sm2 ciphertext layout: c1c3c2(default), c1c2c3 or asn1, convert translates layout to to_layout
+	Layout   string `json:"layout"`
+	ToLayout string `json:"to_layout"`
+	//ed25519/x25519/p256/p384 generate and export: raw(default), pem (PKCS#8/SPKI) or openssh, keys are read in any of them
+	KeyFormat string `json:"key_format"`
+	//ed25519 sign and verify, context selects Ed25519ctx, prehash Ed25519ph
+	Context string `json:"context"`
+	Prehash bool   `json:"prehash"`
+	//derive_shared: optional kdf hkdf (hash, hex salt, info) or sm2, key_len bytes, 32 by default
+	Kdf    string `json:"kdf"`
+	KeyLen int    `json:"key_len"`
+	Salt   string `json:"salt"`
+	Info   string `json:"info"`
+	//sm2 derive_shared runs the GM/T 0003.3 key exchange: role initiator or responder,
+	//this side's ephemeral private key and the peer's ephemeral public key in hex, peer_uid is the peer's user id
+	Role                string `json:"role"`
+	EphemeralKey        string `json:"ephemeral_key"`
+	PeerEphemeralPubkey string `json:"peer_ephemeral_pubkey"`
+	PeerUid             string `json:"peer_uid"`
+}
+
+type KdfBody struct {
+	//pbkdf2, scrypt, argon2id, bcrypt or hkdf
+	Method string `json:"method"`
+	//derive or verify
+	Operation string `json:"operation"`
+	//password or input key material
+	Content string `json:"content"`
+	ContentEncoding
+	//pbkdf2 and hkdf digest: sha256(default), sha512 or sm3
+	Hash string `json:"hash"`
+	//hex, random 16 bytes by default
+	Salt string `json:"salt"`
+	//hkdf only
+	Info   string `json:"info"`
+	KeyLen int    `json:"key_len"`
+	//pbkdf2
+	Iterations int `json:"iterations"`
+	//scrypt
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+	//argon2id, memory in KiB
+	Memory  int `json:"memory"`
+	Time    int `json:"time"`
+	Threads int `json:"threads"`
+	//bcrypt
+	Cost int `json:"cost"`
+	//verify: the PHC or bcrypt string, or the expected hkdf key in output_encoding
+	Encoded string `json:"encoded"`
+}
+
+type MerkleBody struct {
+	//keccak256 (sorted pairs, OpenZeppelin), sha256 (bitcoin, odd node duplicated) or sm3
+	Method string `json:"method"`
+	//build(default) or verify_proof
+	Operation string `json:"operation"`
+	//leaf hashes, or raw data hashed first when hash_leaves is set
+	Leaves     []string `json:"leaves"`
+	HashLeaves bool     `json:"hash_leaves"`
+	ContentEncoding
+	//build: leaves to return inclusion proofs for
+	ProofIndexes []int `json:"proof_indexes"`
+	//verify_proof, index is ignored by keccak256
+	Leaf  string   `json:"leaf"`
+	Index int      `json:"index"`
+	Proof []string `json:"proof"`
+	Root  string   `json:"root"`
+}
+
+type AbiBody struct {
+	//selector, topic, encode, decode, decode_output or decode_log
+	Operation string `json:"operation"`
+	//human readable signature, e.g. "transfer(address to, uint256 amount) returns (bool)",
+	//or a JSON ABI fragment in abi
+	Signature string          `json:"signature"`
+	Abi       json.RawMessage `json:"abi"`
+	//function or event name, required when abi has several
+	Name string `json:"name"`
+	//encode: JSON array of arguments
+	Args json.RawMessage `json:"args"`
+	//hex calldata, return data or log data
+	Data   string   `json:"data"`
+	Topics []string `json:"topics"`
+}
+
+type PipelineBody struct {
+	Steps   []PipelineStep `json:"steps"`
+	Content string         `json:"content"`
+	ContentEncoding
+	//return every intermediate value
+	Trace bool `json:"trace"`
+}
+
+type MagicBody struct {
+	Content string `json:"content"`
+	ContentEncoding
+	//decoders chained at most, default 3
+	Depth int `json:"depth"`
+	//best chains returned, default 5
+	Limit int `json:"limit"`
 }
 
 func CheckRequest(r *http.Request) (reqBytes []byte, err error) {
 
+	reqBytes, err = readRequest(r)
+	fmt.Println("req:   ", string(reqBytes))
+	return
+}
+
+func readRequest(r *http.Request) (reqBytes []byte, err error) {
+
 	if r.Method != "POST" {
 		err = fmt.Errorf("invald http method:%s", r.Method)
 		return
 	}
-	reqBytes, err = ioutil.ReadAll(r.Body)
-	fmt.Println("req:   ", string(reqBytes))
-	return
+	return ioutil.ReadAll(r.Body)
 }
 func CryptoCodecHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -66,6 +233,24 @@ func CryptoCodecHandler(w http.ResponseWriter, r *http.Request) {
 		ErrorResponse(w, http.StatusBadRequest, err)
 		return
 	}
+	if IsBatch(reqBytes) {
+		var cbs []CodecBody
+		err = json.Unmarshal(reqBytes, &cbs)
+		if err != nil {
+			ErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+		if err = CheckBatch(len(cbs)); err != nil {
+			ErrorResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		results := RunBatch(len(cbs), func(i int) (interface{}, error) {
+			return CodecContent(&cbs[i])
+		})
+		bytez, _ := json.Marshal(results)
+		ResultResponse(w, bytez)
+		return
+	}
 	var cb CodecBody
 	err = json.Unmarshal(reqBytes, &cb)
 	if err != nil {
@@ -73,19 +258,8 @@ func CryptoCodecHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var hr HttpResult
-	switch cb.Method {
-	case "base64_encode":
-		uEnc := base64.URLEncoding.EncodeToString([]byte(cb.Content))
-		hr.Result = uEnc
-	case "base64_decode":
-		uDec, err := base64.URLEncoding.DecodeString(cb.Content)
-		if err != nil {
-			ErrorResponse(w, http.StatusBadRequest, err)
-			return
-		}
-		hr.Result = string(uDec)
-	default:
-		err = fmt.Errorf("invalid crypto method: %s", cb.Method)
+	hr.Result, err = CodecContent(&cb)
+	if err != nil {
 		ErrorResponse(w, http.StatusBadRequest, err)
 		return
 	}
@@ -100,6 +274,24 @@ func CryptoAsymmetricHandler(w http.ResponseWriter, r *http.Request) {
 		ErrorResponse(w, http.StatusBadRequest, err)
 		return
 	}
+	if IsBatch(reqBytes) {
+		var abs []AsymmetricBody
+		err = json.Unmarshal(reqBytes, &abs)
+		if err != nil {
+			ErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+		if err = CheckBatch(len(abs)); err != nil {
+			ErrorResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		results := RunBatch(len(abs), func(i int) (interface{}, error) {
+			return AsymmetricContent(&abs[i])
+		})
+		bytez, _ := json.Marshal(results)
+		ResultResponse(w, bytez)
+		return
+	}
 	var ab AsymmetricBody
 	err = json.Unmarshal(reqBytes, &ab)
 	if err != nil {
@@ -107,43 +299,8 @@ func CryptoAsymmetricHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var hr HttpResult
-	switch ab.Method {
-	case "secp256k1":
-		if ab.Operation == "generate" {
-			privkey, err := crypto.GenerateKey()
-			if err != nil {
-				ErrorResponse(w, http.StatusInternalServerError, err)
-				return
-			}
-			var result struct {
-				Privkey string `json:"privkey"`
-				Pubkey  string `json:"pubkey"`
-				Address string `json:"address"`
-			}
-			address := crypto.PubkeyToAddress(privkey.PublicKey)
-			result.Privkey = common.Bytes2Hex(crypto.FromECDSA(privkey))
-			result.Pubkey = common.Bytes2Hex(crypto.FromECDSAPub(&privkey.PublicKey))
-			result.Address = address.Hex()
-			hr.Result = result
-		} else if ab.Operation == "get_address" {
-			privBytes := common.Hex2Bytes(ab.Content)
-			privkey, err := crypto.ToECDSA(privBytes)
-			if err != nil {
-				ErrorResponse(w, http.StatusInternalServerError, err)
-				return
-			}
-			address := crypto.PubkeyToAddress(privkey.PublicKey)
-			var result struct {
-				Privkey string `json:"privkey"`
-				Address string `json:"address"`
-			}
-			result.Privkey = ab.Content
-			result.Address = address.Hex()
-			hr.Result = result
-
-		}
-	default:
-		err = fmt.Errorf("invalid crypto method: %s", ab.Method)
+	hr.Result, err = AsymmetricContent(&ab)
+	if err != nil {
 		ErrorResponse(w, http.StatusBadRequest, err)
 		return
 	}
@@ -157,6 +314,24 @@ func CryptoHashHandler(w http.ResponseWriter, r *http.Request) {
 		ErrorResponse(w, http.StatusBadRequest, err)
 		return
 	}
+	if IsBatch(reqBytes) {
+		var hbs []HashBody
+		err = json.Unmarshal(reqBytes, &hbs)
+		if err != nil {
+			ErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+		if err = CheckBatch(len(hbs)); err != nil {
+			ErrorResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		results := RunBatch(len(hbs), func(i int) (interface{}, error) {
+			return HashContent(&hbs[i])
+		})
+		bytez, _ := json.Marshal(results)
+		ResultResponse(w, bytez)
+		return
+	}
 	var hb HashBody
 	err = json.Unmarshal(reqBytes, &hb)
 	if err != nil {
@@ -164,23 +339,196 @@ func CryptoHashHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var hr HttpResult
-	switch hb.Method {
-	case "md5":
-		m := md5.New()
-		m.Write([]byte(hb.Content))
-		hr.Result = hex.EncodeToString(m.Sum(nil))
-	default:
-		err = fmt.Errorf("invalid crypto method: %s", hb.Method)
+	hr.Result, err = HashContent(&hb)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	bytez, _ := json.Marshal(hr)
+	ResultResponse(w, bytez)
+}
+
+// CryptoKdfHandler never logs request or response bodies, they carry passwords and derived keys
+func CryptoKdfHandler(w http.ResponseWriter, r *http.Request) {
+
+	reqBytes, err := readRequest(r)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var kb KdfBody
+	err = json.Unmarshal(reqBytes, &kb)
+	if err != nil {
+		ErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	var hr HttpResult
+	hr.Result, err = KdfContent(&kb)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	bytez, _ := json.Marshal(hr)
+	writeResult(w, bytez)
+}
+
+func CryptoMerkleHandler(w http.ResponseWriter, r *http.Request) {
+
+	reqBytes, err := CheckRequest(r)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var mb MerkleBody
+	err = json.Unmarshal(reqBytes, &mb)
+	if err != nil {
+		ErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	var hr HttpResult
+	hr.Result, err = MerkleContent(&mb)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	bytez, _ := json.Marshal(hr)
+	ResultResponse(w, bytez)
+}
+
+func CryptoAbiHandler(w http.ResponseWriter, r *http.Request) {
+
+	reqBytes, err := CheckRequest(r)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var ab AbiBody
+	err = json.Unmarshal(reqBytes, &ab)
+	if err != nil {
+		ErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	var hr HttpResult
+	hr.Result, err = AbiContent(&ab)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	bytez, _ := json.Marshal(hr)
+	ResultResponse(w, bytez)
+}
+
+func CryptoPipelineHandler(w http.ResponseWriter, r *http.Request) {
+
+	reqBytes, err := CheckRequest(r)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var pb PipelineBody
+	err = json.Unmarshal(reqBytes, &pb)
+	if err != nil {
+		ErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	var hr HttpResult
+	hr.Result, err = RunPipeline(&pb)
+	if err != nil {
 		ErrorResponse(w, http.StatusBadRequest, err)
 		return
 	}
 	bytez, _ := json.Marshal(hr)
 	ResultResponse(w, bytez)
 }
+
+func CryptoMagicHandler(w http.ResponseWriter, r *http.Request) {
+
+	reqBytes, err := CheckRequest(r)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var mb MagicBody
+	err = json.Unmarshal(reqBytes, &mb)
+	if err != nil {
+		ErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	var hr HttpResult
+	hr.Result, err = Magic(&mb)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	bytez, _ := json.Marshal(hr)
+	ResultResponse(w, bytez)
+}
+
+// CryptoHashStreamHandler hashes the raw body, or the first file of a multipart body,
+// e.g. POST /crypto/hash/stream?method=sha256&method=sm3
+func CryptoHashStreamHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "POST" {
+		err := fmt.Errorf("invald http method:%s", r.Method)
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	query := r.URL.Query()
+	var methods []string
+	for _, method := range query["method"] {
+		methods = append(methods, strings.Split(method, ",")...)
+	}
+	var body io.Reader = r.Body
+	if mr, err := r.MultipartReader(); err == nil {
+		part, err := mr.NextPart()
+		for err == nil && part.FileName() == "" {
+			part, err = mr.NextPart()
+		}
+		if err != nil {
+			ErrorResponse(w, http.StatusBadRequest, fmt.Errorf("no file in multipart body: %v", err))
+			return
+		}
+		body = part
+	}
+	var hr HttpResult
+	result, err := StreamHash(body, methods, query.Get("output_encoding"))
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	hr.Result = result
+	bytez, _ := json.Marshal(hr)
+	ResultResponse(w, bytez)
+}
 func main() {
+	app := cli.NewApp()
+	app.Name = "tools"
+	app.Usage = "crypto http service, run without a command to serve"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "listen, l",
+			Value: "0.0.0.0:12580",
+			Usage: "http listen address",
+		},
+	}
+	app.Action = serve
+	app.Commands = []cli.Command{asciiCommand}
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func serve(ctx *cli.Context) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/crypto/hash", CryptoHashHandler)
+	mux.HandleFunc("/crypto/hash/stream", CryptoHashStreamHandler)
 	mux.HandleFunc("/crypto/codec", CryptoCodecHandler)
 	mux.HandleFunc("/crypto/asymmetric", CryptoAsymmetricHandler)
-	http.ListenAndServe("0.0.0.0:12580", mux)
+	mux.HandleFunc("/crypto/kdf", CryptoKdfHandler)
+	mux.HandleFunc("/crypto/merkle", CryptoMerkleHandler)
+	mux.HandleFunc("/crypto/abi", CryptoAbiHandler)
+	mux.HandleFunc("/crypto/pipeline", CryptoPipelineHandler)
+	mux.HandleFunc("/crypto/magic", CryptoMagicHandler)
+	return http.ListenAndServe(ctx.String("listen"), mux)
 }
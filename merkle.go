@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+const MaxMerkleLeaves = 1 << 17
+
+type merkleScheme struct {
+	hash string
+	//hash twice, as bitcoin does
+	double bool
+	//sort every pair before hashing, as OpenZeppelin MerkleProof does
+	sorted bool
+	//pair the last node with itself on odd levels instead of promoting it
+	duplicateOdd bool
+}
+
+// leaves and nodes of sha256 are in internal byte order, i.e. reversed bitcoin txid hex
+var merkleSchemes = map[string]*merkleScheme{
+	"keccak256": {hash: "keccak256", sorted: true},
+	"sha256":    {hash: "sha256", double: true, duplicateOdd: true},
+	"sm3":       {hash: "sm3", duplicateOdd: true},
+}
+
+func (s *merkleScheme) sum(data []byte) []byte {
+	digest, _ := HashSum(s.hash, data)
+	if s.double {
+		digest, _ = HashSum(s.hash, digest)
+	}
+	return digest
+}
+
+func (s *merkleScheme) pair(a, b []byte) []byte {
+	if s.sorted && bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return s.sum(append(append([]byte{}, a...), b...))
+}
+
+// levels returns every level of the tree, leaves first and root last
+func (s *merkleScheme) levels(leaves [][]byte) [][][]byte {
+	levels := [][][]byte{leaves}
+	for level := leaves; len(level) > 1; {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			switch {
+			case i+1 < len(level):
+				next = append(next, s.pair(level[i], level[i+1]))
+			case s.duplicateOdd:
+				next = append(next, s.pair(level[i], level[i]))
+			default:
+				next = append(next, level[i])
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+func (s *merkleScheme) proof(levels [][][]byte, index int) [][]byte {
+	var proof [][]byte
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, level[sibling])
+		} else if s.duplicateOdd {
+			proof = append(proof, level[index])
+		}
+		index /= 2
+	}
+	return proof
+}
+
+func (s *merkleScheme) verify(leaf []byte, proof [][]byte, index int, root []byte) bool {
+	node := leaf
+	for _, sibling := range proof {
+		if index%2 == 0 {
+			node = s.pair(node, sibling)
+		} else {
+			node = s.pair(sibling, node)
+		}
+		index /= 2
+	}
+	return bytes.Equal(node, root)
+}
+
+type MerkleProof struct {
+	Index int      `json:"index"`
+	Leaf  string   `json:"leaf"`
+	Proof []string `json:"proof"`
+}
+
+type MerkleResult struct {
+	Root   string        `json:"root"`
+	Leaves []string      `json:"leaves,omitempty"`
+	Proofs []MerkleProof `json:"proofs,omitempty"`
+}
+
+func MerkleContent(mb *MerkleBody) (interface{}, error) {
+	scheme, ok := merkleSchemes[mb.Method]
+	if !ok {
+		return nil, fmt.Errorf("invalid merkle method: %s", mb.Method)
+	}
+	switch mb.Operation {
+	case "", "build":
+		return merkleBuild(scheme, mb)
+	case "verify_proof":
+		return merkleVerify(scheme, mb)
+	}
+	return nil, fmt.Errorf("invalid merkle operation: %s", mb.Operation)
+}
+
+func merkleBuild(scheme *merkleScheme, mb *MerkleBody) (interface{}, error) {
+	if len(mb.Leaves) == 0 {
+		return nil, errors.New("no merkle leaves")
+	}
+	if len(mb.Leaves) > MaxMerkleLeaves {
+		return nil, fmt.Errorf("too many merkle leaves: %d > %d", len(mb.Leaves), MaxMerkleLeaves)
+	}
+	leaves := make([][]byte, len(mb.Leaves))
+	for i, content := range mb.Leaves {
+		leaf, err := merkleLeaf(scheme, mb, content)
+		if err != nil {
+			return nil, fmt.Errorf("leaf %d: %v", i, err)
+		}
+		leaves[i] = leaf
+	}
+	levels := scheme.levels(leaves)
+	var result MerkleResult
+	var err error
+	result.Root, err = EncodeContent(levels[len(levels)-1][0], mb.OutputEncoding, EncodingHex)
+	if err != nil {
+		return nil, err
+	}
+	if mb.HashLeaves {
+		for _, leaf := range leaves {
+			encoded, _ := EncodeContent(leaf, mb.OutputEncoding, EncodingHex)
+			result.Leaves = append(result.Leaves, encoded)
+		}
+	}
+	for _, index := range mb.ProofIndexes {
+		if index < 0 || index >= len(leaves) {
+			return nil, fmt.Errorf("proof index out of range: %d", index)
+		}
+		proof := MerkleProof{Index: index, Proof: []string{}}
+		proof.Leaf, _ = EncodeContent(leaves[index], mb.OutputEncoding, EncodingHex)
+		for _, node := range scheme.proof(levels, index) {
+			encoded, _ := EncodeContent(node, mb.OutputEncoding, EncodingHex)
+			proof.Proof = append(proof.Proof, encoded)
+		}
+		result.Proofs = append(result.Proofs, proof)
+	}
+	return &result, nil
+}
+
+func merkleVerify(scheme *merkleScheme, mb *MerkleBody) (interface{}, error) {
+	leaf, err := merkleLeaf(scheme, mb, mb.Leaf)
+	if err != nil {
+		return nil, err
+	}
+	//root and proof are in output_encoding, the same as build returns them
+	root, err := DecodeContent("root", mb.Root, mb.OutputEncoding, EncodingHex)
+	if err != nil {
+		return nil, err
+	}
+	proof := make([][]byte, len(mb.Proof))
+	for i, node := range mb.Proof {
+		proof[i], err = DecodeContent(fmt.Sprintf("proof %d", i), node, mb.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scheme.verify(leaf, proof, mb.Index, root), nil
+}
+
+func merkleLeaf(scheme *merkleScheme, mb *MerkleBody, content string) ([]byte, error) {
+	if mb.HashLeaves {
+		data, err := DecodeContent("leaf", content, mb.InputEncoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		return scheme.sum(data), nil
+	}
+	return DecodeContent("leaf", content, mb.InputEncoding, EncodingHex)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/tjfoc/gmsm/sm3"
+)
+
+// reverseHex turns a bitcoin txid or block merkle root into internal byte order
+func reverseHex(s string) string {
+	b, _ := hex.DecodeString(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return hex.EncodeToString(b)
+}
+
+// referenceMerkleRoot builds the tree level by level with the hash functions called directly
+func referenceMerkleRoot(method string, level [][]byte) []byte {
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			a := level[i]
+			if i+1 == len(level) {
+				if method == "keccak256" {
+					next = append(next, a)
+					continue
+				}
+				level = append(level, a)
+			}
+			b := level[i+1]
+			switch method {
+			case "keccak256":
+				if bytes.Compare(a, b) > 0 {
+					a, b = b, a
+				}
+				next = append(next, crypto.Keccak256(a, b))
+			case "sha256":
+				first := sha256.Sum256(append(append([]byte{}, a...), b...))
+				second := sha256.Sum256(first[:])
+				next = append(next, second[:])
+			case "sm3":
+				next = append(next, sm3.Sm3Sum(append(append([]byte{}, a...), b...)))
+			}
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// openZeppelinVerify is MerkleProof.verify, processProof hashes every sibling with commutative keccak256
+func openZeppelinVerify(proof [][]byte, root, leaf []byte) bool {
+	node := leaf
+	for _, sibling := range proof {
+		a, b := node, sibling
+		if bytes.Compare(a, b) > 0 {
+			a, b = b, a
+		}
+		node = crypto.Keccak256(a, b)
+	}
+	return bytes.Equal(node, root)
+}
+
+func TestMerkleSchemes(t *testing.T) {
+	for method := range merkleSchemes {
+		for _, n := range []int{1, 2, 3, 5} {
+			leaves := make([]string, n)
+			raw := make([][]byte, n)
+			indexes := make([]int, n)
+			for i := range leaves {
+				raw[i] = crypto.Keccak256([]byte{byte(i)})
+				leaves[i] = hex.EncodeToString(raw[i])
+				indexes[i] = i
+			}
+			result, err := MerkleContent(&MerkleBody{Method: method, Leaves: leaves, ProofIndexes: indexes})
+			assert.Nil(t, err)
+			built := result.(*MerkleResult)
+			assert.Equal(t, hex.EncodeToString(referenceMerkleRoot(method, raw)), built.Root, "%s %d leaves", method, n)
+			assert.Equal(t, n, len(built.Proofs))
+
+			for _, p := range built.Proofs {
+				assert.Equal(t, leaves[p.Index], p.Leaf)
+				vb := &MerkleBody{Method: method, Operation: "verify_proof", Leaf: p.Leaf, Index: p.Index, Proof: p.Proof, Root: built.Root}
+				valid, err := MerkleContent(vb)
+				assert.Nil(t, err)
+				assert.Equal(t, true, valid, "%s %d leaves, index %d", method, n, p.Index)
+
+				if method == "keccak256" {
+					proof := make([][]byte, len(p.Proof))
+					for i, node := range p.Proof {
+						proof[i], _ = hex.DecodeString(node)
+					}
+					root, _ := hex.DecodeString(built.Root)
+					assert.True(t, openZeppelinVerify(proof, root, raw[p.Index]))
+				}
+
+				if len(p.Proof) == 0 {
+					continue
+				}
+				tampered := append([]string{}, p.Proof...)
+				node, _ := hex.DecodeString(tampered[0])
+				node[0] ^= 1
+				tampered[0] = hex.EncodeToString(node)
+				vb.Proof = tampered
+				valid, err = MerkleContent(vb)
+				assert.Nil(t, err)
+				assert.Equal(t, false, valid, "%s %d leaves, index %d tampered", method, n, p.Index)
+			}
+		}
+	}
+}
+
+// the first example of the OpenZeppelin merkle-tree README, leaves are keccak256(keccak256(abi.encode(address, uint256)))
+func TestMerkleOpenZeppelin(t *testing.T) {
+	values := []struct {
+		address string
+		amount  string
+	}{
+		{"0x1111111111111111111111111111111111111111", "5000000000000000000"},
+		{"0x2222222222222222222222222222222222222222", "2500000000000000000"},
+	}
+	var leaves []string
+	for _, v := range values {
+		amount, _ := new(big.Int).SetString(v.amount, 10)
+		encoded := append(common.LeftPadBytes(common.HexToAddress(v.address).Bytes(), 32), common.LeftPadBytes(amount.Bytes(), 32)...)
+		leaves = append(leaves, hex.EncodeToString(crypto.Keccak256(crypto.Keccak256(encoded))))
+	}
+	result, err := MerkleContent(&MerkleBody{Method: "keccak256", Leaves: leaves})
+	assert.Nil(t, err)
+	assert.Equal(t, "d4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77", result.(*MerkleResult).Root)
+}
+
+func TestMerkleBitcoin(t *testing.T) {
+	blocks := []struct {
+		txids []string
+		root  string
+	}{
+		// genesis block
+		{[]string{"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"},
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"},
+		// block 100000
+		{[]string{
+			"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+			"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+			"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+			"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+		}, "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766"},
+	}
+	for _, block := range blocks {
+		var leaves []string
+		for _, txid := range block.txids {
+			leaves = append(leaves, reverseHex(txid))
+		}
+		result, err := MerkleContent(&MerkleBody{Method: "sha256", Leaves: leaves, ProofIndexes: []int{len(leaves) - 1}})
+		assert.Nil(t, err)
+		built := result.(*MerkleResult)
+		assert.Equal(t, block.root, reverseHex(built.Root))
+
+		p := built.Proofs[0]
+		valid, err := MerkleContent(&MerkleBody{Method: "sha256", Operation: "verify_proof", Leaf: p.Leaf, Index: p.Index, Proof: p.Proof, Root: built.Root})
+		assert.Nil(t, err)
+		assert.Equal(t, true, valid)
+		if len(p.Proof) > 0 {
+			// the index picks the side of every sibling, a wrong one fails for sha256
+			valid, err = MerkleContent(&MerkleBody{Method: "sha256", Operation: "verify_proof", Leaf: p.Leaf, Index: p.Index - 1, Proof: p.Proof, Root: built.Root})
+			assert.Nil(t, err)
+			assert.Equal(t, false, valid)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tjfoc/gmsm/sm4"
+)
+
+const (
+	MaxPipelineSteps = 32
+	//every intermediate value and the trace as a whole stay below it
+	MaxPipelineBytes = MaxDecompressedSize
+)
+
+type PipelineStep struct {
+	//<codec>_encode, <codec>_decode, <method>_compress, <method>_decompress, hash, hmac, checksum,
+	//aes_encrypt, aes_decrypt, sm4_encrypt or sm4_decrypt
+	Op string `json:"op"`
+	//hash, hmac and checksum method
+	Method string `json:"method"`
+	//hmac and keyed hash key (utf8 by default), cipher key (hex by default)
+	Key         string `json:"key"`
+	KeyEncoding string `json:"key_encoding"`
+	//cipher mode: cbc(default, PKCS#7 padding), ctr or gcm
+	Mode string `json:"mode"`
+	//hex iv, the nonce for gcm
+	IV    string `json:"iv"`
+	Size  int    `json:"size"`
+	Level int    `json:"level"`
+}
+
+type PipelineTrace struct {
+	Step   int    `json:"step"`
+	Op     string `json:"op"`
+	Length int    `json:"length"`
+	Hex    string `json:"hex"`
+	Text   string `json:"text,omitempty"`
+}
+
+type PipelineResult struct {
+	Output string          `json:"output"`
+	Trace  []PipelineTrace `json:"trace,omitempty"`
+}
+
+// pipelineOps holds the steps that are not plain text codecs
+var pipelineOps = map[string]func(step *PipelineStep, data []byte) ([]byte, error){
+	"hash": func(step *PipelineStep, data []byte) ([]byte, error) {
+		key, err := DecodeContent("key", step.Key, step.KeyEncoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		hasher, err := NewHasherWithOptions(step.Method, step.Size, key)
+		if err != nil {
+			return nil, err
+		}
+		hasher.Write(data)
+		return hasher.Sum(nil), nil
+	},
+	"hmac": func(step *PipelineStep, data []byte) ([]byte, error) {
+		key, err := DecodeContent("key", step.Key, step.KeyEncoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		return HMACSum(step.Method, key, data)
+	},
+	"checksum": func(step *PipelineStep, data []byte) ([]byte, error) {
+		return ChecksumSum(step.Method, data)
+	},
+}
+
+// pipelineCiphers are the block ciphers of the <cipher>_encrypt and <cipher>_decrypt steps
+var pipelineCiphers = map[string]func(key []byte) (cipher.Block, error){
+	"aes": aes.NewCipher,
+	"sm4": sm4.NewCipher,
+}
+
+func runPipelineCipher(name string, step *PipelineStep, data []byte, encrypt bool) ([]byte, error) {
+	key, err := DecodeContent("key", step.Key, step.KeyEncoding, EncodingHex)
+	if err != nil {
+		return nil, err
+	}
+	block, err := pipelineCiphers[name](key)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := DecodeContent("iv", step.IV, EncodingHex, EncodingHex)
+	if err != nil {
+		return nil, err
+	}
+	size := block.BlockSize()
+	switch step.Mode {
+	case "", "cbc":
+		if len(iv) != size {
+			return nil, fmt.Errorf("invalid iv length: %d, want %d", len(iv), size)
+		}
+		if encrypt {
+			padding := size - len(data)%size
+			out := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+			cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, out)
+			return out, nil
+		}
+		if len(data) == 0 || len(data)%size != 0 {
+			return nil, fmt.Errorf("invalid ciphertext length: %d, want a multiple of %d", len(data), size)
+		}
+		out := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+		padding := int(out[len(out)-1])
+		if padding == 0 || padding > size || !bytes.Equal(out[len(out)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+			return nil, errors.New("invalid padding")
+		}
+		return out[:len(out)-padding], nil
+	case "ctr":
+		if len(iv) != size {
+			return nil, fmt.Errorf("invalid iv length: %d, want %d", len(iv), size)
+		}
+		out := make([]byte, len(data))
+		cipher.NewCTR(block, iv).XORKeyStream(out, data)
+		return out, nil
+	case "gcm":
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(iv) != aead.NonceSize() {
+			return nil, fmt.Errorf("invalid iv length: %d, want %d", len(iv), aead.NonceSize())
+		}
+		if encrypt {
+			return aead.Seal(nil, iv, data, nil), nil
+		}
+		return aead.Open(nil, iv, data, nil)
+	}
+	return nil, fmt.Errorf("invalid mode: %s", step.Mode)
+}
+
+func runPipelineStep(step *PipelineStep, data []byte) ([]byte, error) {
+	if op, ok := pipelineOps[step.Op]; ok {
+		return op(step, data)
+	}
+	if name := strings.TrimSuffix(step.Op, "_encrypt"); name != step.Op && pipelineCiphers[name] != nil {
+		return runPipelineCipher(name, step, data, true)
+	}
+	if name := strings.TrimSuffix(step.Op, "_decrypt"); name != step.Op && pipelineCiphers[name] != nil {
+		return runPipelineCipher(name, step, data, false)
+	}
+	if name := strings.TrimSuffix(step.Op, "_encode"); name != step.Op && textCodecs[name] != nil {
+		return []byte(textCodecs[name].Encode(data)), nil
+	}
+	if name := strings.TrimSuffix(step.Op, "_decode"); name != step.Op && textCodecs[name] != nil {
+		return textCodecs[name].Decode(strings.TrimSpace(string(data)))
+	}
+	if name := strings.TrimSuffix(step.Op, "_compress"); name != step.Op && compressors[name] != nil {
+		return Compress(name, data, step.Level)
+	}
+	if name := strings.TrimSuffix(step.Op, "_decompress"); name != step.Op && compressors[name] != nil {
+		return Decompress(name, data)
+	}
+	return nil, fmt.Errorf("invalid pipeline op: %s", step.Op)
+}
+
+// RunPipeline feeds the binary output of every step into the next one
+func RunPipeline(pb *PipelineBody) (*PipelineResult, error) {
+	if len(pb.Steps) == 0 {
+		return nil, errors.New("no pipeline steps")
+	}
+	if len(pb.Steps) > MaxPipelineSteps {
+		return nil, fmt.Errorf("too many pipeline steps: %d > %d", len(pb.Steps), MaxPipelineSteps)
+	}
+	data, err := DecodeContent("content", pb.Content, pb.InputEncoding, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+	result := &PipelineResult{}
+	traceBytes := 0
+	for i := range pb.Steps {
+		step := &pb.Steps[i]
+		data, err = runPipelineStep(step, data)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %v", i, step.Op, err)
+		}
+		if len(data) > MaxPipelineBytes {
+			return nil, fmt.Errorf("step %d (%s): output of %d bytes exceeds %d", i, step.Op, len(data), MaxPipelineBytes)
+		}
+		if pb.Trace {
+			//the hex and the text of every step count towards the cap
+			text := utf8.Valid(data)
+			if traceBytes += 2 * len(data); text {
+				traceBytes += len(data)
+			}
+			if traceBytes > MaxPipelineBytes {
+				return nil, fmt.Errorf("step %d (%s): trace of %d bytes exceeds %d", i, step.Op, traceBytes, MaxPipelineBytes)
+			}
+			trace := PipelineTrace{Step: i, Op: step.Op, Length: len(data), Hex: fmt.Sprintf("%x", data)}
+			if text {
+				trace.Text = string(data)
+			}
+			result.Trace = append(result.Trace, trace)
+		}
+	}
+	result.Output, err = EncodeContent(data, pb.OutputEncoding, EncodingUTF8)
+	return result, err
+}
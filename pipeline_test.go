@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineTrace(t *testing.T) {
+	gz, err := Compress("gzip", []byte("hello"), 0)
+	assert.Nil(t, err)
+	digest := sha256.Sum256([]byte("68656c6c6f"))
+
+	result, err := RunPipeline(&PipelineBody{
+		Steps: []PipelineStep{
+			{Op: "base64std_decode"},
+			{Op: "gzip_decompress"},
+			{Op: "hex_encode"},
+			{Op: "hash", Method: "sha256"},
+		},
+		Content:         base64.StdEncoding.EncodeToString(gz),
+		ContentEncoding: ContentEncoding{OutputEncoding: EncodingHex},
+		Trace:           true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, hex.EncodeToString(digest[:]), result.Output)
+	assert.Equal(t, 4, len(result.Trace))
+	assert.Equal(t, PipelineTrace{Step: 0, Op: "base64std_decode", Length: len(gz), Hex: hex.EncodeToString(gz)}, result.Trace[0])
+	assert.Equal(t, PipelineTrace{Step: 1, Op: "gzip_decompress", Length: 5, Hex: "68656c6c6f", Text: "hello"}, result.Trace[1])
+	assert.Equal(t, PipelineTrace{Step: 2, Op: "hex_encode", Length: 10, Hex: "36383635366336633666", Text: "68656c6c6f"}, result.Trace[2])
+	assert.Equal(t, 32, result.Trace[3].Length)
+	assert.Equal(t, result.Output, result.Trace[3].Hex)
+
+	result, err = RunPipeline(&PipelineBody{Steps: []PipelineStep{{Op: "hex_encode"}}, Content: "hello"})
+	assert.Nil(t, err)
+	assert.Nil(t, result.Trace)
+}
+
+func TestPipelineStepErrors(t *testing.T) {
+	tests := []struct {
+		steps   []PipelineStep
+		content string
+		err     string
+	}{
+		{nil, "", "no pipeline steps"},
+		{make([]PipelineStep, MaxPipelineSteps+1), "", "too many pipeline steps: 33 > 32"},
+		{[]PipelineStep{{Op: "hex_encode"}, {Op: "rot13"}}, "a", "step 1 (rot13): invalid pipeline op: rot13"},
+		{[]PipelineStep{{Op: "hex_encode"}, {Op: "gzip_decompress"}}, "a", "step 1 (gzip_decompress): invalid gzip data"},
+		{[]PipelineStep{{Op: "hex_decode"}}, "zz", "step 0 (hex_decode): encoding/hex: invalid byte"},
+		{[]PipelineStep{{Op: "hash", Method: "md4"}}, "a", "step 0 (hash):"},
+		{[]PipelineStep{{Op: "aes_encrypt", Key: "00"}}, "a", "step 0 (aes_encrypt): crypto/aes: invalid key size 1"},
+		{[]PipelineStep{{Op: "aes_encrypt", Key: strings.Repeat("00", 16), IV: "00"}}, "a", "step 0 (aes_encrypt): invalid iv length: 1, want 16"},
+		{[]PipelineStep{{Op: "sm4_encrypt", Key: strings.Repeat("00", 16), Mode: "ecb"}}, "a", "step 0 (sm4_encrypt): invalid mode: ecb"},
+		{[]PipelineStep{{Op: "sm4_decrypt", Key: strings.Repeat("00", 16), IV: strings.Repeat("00", 16)}}, "a", "step 0 (sm4_decrypt): invalid ciphertext length: 1, want a multiple of 16"},
+	}
+	for _, test := range tests {
+		_, err := RunPipeline(&PipelineBody{Steps: test.steps, Content: test.content})
+		assert.ErrorContains(t, err, test.err)
+	}
+}
+
+func TestPipelineCiphers(t *testing.T) {
+	//NIST SP 800-38A F.2.1 and F.5.1
+	aesKey := "2b7e151628aed2a6abf7158809cf4f3c"
+	block := "6bc1bee22e409f96e93d7e117393172a"
+	//GB/T 32907 appendix A encrypts the key itself, cbc with a zero iv encrypts the first block like ecb
+	sm4Key := "0123456789abcdeffedcba9876543210"
+	tests := []struct {
+		op     string
+		step   PipelineStep
+		block  string
+		prefix string
+	}{
+		{"aes", PipelineStep{Key: aesKey, IV: "000102030405060708090a0b0c0d0e0f"}, block, "7649abac8119b246cee98e9b12e9197d"},
+		{"aes", PipelineStep{Key: aesKey, IV: "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff", Mode: "ctr"}, block, "874d6191b620e3261bef6864990db6ce"},
+		{"sm4", PipelineStep{Key: sm4Key, IV: strings.Repeat("00", 16)}, sm4Key, "681edf34d206965e86b3e94f536e4246"},
+		{"sm4", PipelineStep{Key: sm4Key, IV: strings.Repeat("00", 16), Mode: "ctr"}, sm4Key, ""},
+		{"aes", PipelineStep{Key: aesKey, IV: strings.Repeat("00", 12), Mode: "gcm"}, block, ""},
+		{"sm4", PipelineStep{Key: sm4Key, IV: strings.Repeat("00", 12), Mode: "gcm"}, sm4Key, ""},
+	}
+	for _, test := range tests {
+		encrypt, decrypt := test.step, test.step
+		encrypt.Op, decrypt.Op = test.op+"_encrypt", test.op+"_decrypt"
+		content := test.block
+		if test.step.Mode == "" {
+			content = test.block + "0102"
+		}
+		result, err := RunPipeline(&PipelineBody{Steps: []PipelineStep{encrypt}, Content: content,
+			ContentEncoding: ContentEncoding{InputEncoding: EncodingHex, OutputEncoding: EncodingHex}})
+		if !assert.Nil(t, err, encrypt) {
+			continue
+		}
+		assert.True(t, strings.HasPrefix(result.Output, test.prefix), result.Output)
+		if test.step.Mode == "" {
+			//two bytes over a block pad to a second block
+			assert.Equal(t, 64, len(result.Output))
+		}
+
+		result, err = RunPipeline(&PipelineBody{Steps: []PipelineStep{decrypt}, Content: result.Output,
+			ContentEncoding: ContentEncoding{InputEncoding: EncodingHex, OutputEncoding: EncodingHex}})
+		assert.Nil(t, err)
+		assert.Equal(t, content, result.Output)
+	}
+
+	gcm := PipelineStep{Key: aesKey, IV: strings.Repeat("00", 12), Mode: "gcm"}
+	encrypt, decrypt := gcm, gcm
+	encrypt.Op, decrypt.Op = "aes_encrypt", "aes_decrypt"
+	decrypt.Key = strings.Repeat("00", 16)
+	_, err := RunPipeline(&PipelineBody{Steps: []PipelineStep{encrypt, decrypt}, Content: "tampered"})
+	assert.EqualError(t, err, "step 1 (aes_decrypt): cipher: message authentication failed")
+}
+
+func TestPipelineSizeCap(t *testing.T) {
+	//every hex_encode doubles "a", 2^25 bytes is the cap itself
+	steps := make([]PipelineStep, 26)
+	for i := range steps {
+		steps[i].Op = "hex_encode"
+	}
+	_, err := RunPipeline(&PipelineBody{Steps: steps, Content: "a"})
+	assert.EqualError(t, err, "step 25 (hex_encode): output of 67108864 bytes exceeds 33554432")
+
+	//the trace keeps hex and text of every step, 3*(2^24-2) bytes after step 22
+	_, err = RunPipeline(&PipelineBody{Steps: steps[:23], Content: "a", Trace: true})
+	assert.EqualError(t, err, "step 22 (hex_encode): trace of 50331642 bytes exceeds 33554432")
+	result, err := RunPipeline(&PipelineBody{Steps: steps[:22], Content: "a", Trace: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 22, len(result.Trace))
+}
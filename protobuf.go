@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const MaxProtobufDepth = 32
+
+var protobufWireTypes = map[protowire.Type]string{
+	protowire.VarintType:     "varint",
+	protowire.Fixed64Type:    "fixed64",
+	protowire.BytesType:      "bytes",
+	protowire.StartGroupType: "group",
+	protowire.Fixed32Type:    "fixed32",
+}
+
+// ProtobufField is one field of a message decoded without its schema
+type ProtobufField struct {
+	Offset   int              `json:"offset"`
+	Field    int              `json:"field"`
+	WireType string           `json:"wire_type"`
+	Value    interface{}      `json:"value,omitempty"`
+	Message  []*ProtobufField `json:"message,omitempty"`
+	//other readings of the same bits, e.g. zigzag sint or float
+	Alternatives map[string]interface{} `json:"alternatives,omitempty"`
+}
+
+func DecodeProtobufWire(data []byte) ([]*ProtobufField, error) {
+	return decodeProtobufMessage(data, 0, 0)
+}
+
+func decodeProtobufMessage(data []byte, base, depth int) ([]*ProtobufField, error) {
+	if depth > MaxProtobufDepth {
+		return nil, fmt.Errorf("offset %d: nested deeper than %d", base, MaxProtobufDepth)
+	}
+	fields := []*ProtobufField{}
+	for pos := 0; pos < len(data); {
+		num, typ, n := protowire.ConsumeTag(data[pos:])
+		if n < 0 {
+			return nil, fmt.Errorf("offset %d: invalid tag: %v", base+pos, protowire.ParseError(n))
+		}
+		field := &ProtobufField{Offset: base + pos, Field: int(num), WireType: protobufWireTypes[typ]}
+		pos += n
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data[pos:])
+			if n < 0 {
+				return nil, fmt.Errorf("offset %d: field %d: %v", base+pos, num, protowire.ParseError(n))
+			}
+			pos += n
+			field.Value = fmt.Sprint(v)
+			field.Alternatives = map[string]interface{}{"sint": fmt.Sprint(protowire.DecodeZigZag(v))}
+			if int64(v) < 0 {
+				field.Alternatives["int"] = fmt.Sprint(int64(v))
+			}
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(data[pos:])
+			if n < 0 {
+				return nil, fmt.Errorf("offset %d: field %d: %v", base+pos, num, protowire.ParseError(n))
+			}
+			pos += n
+			field.Value = fmt.Sprint(v)
+			field.Alternatives = map[string]interface{}{"sfixed32": fmt.Sprint(int32(v)), "float": jsonFloat(float64(math.Float32frombits(v)))}
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(data[pos:])
+			if n < 0 {
+				return nil, fmt.Errorf("offset %d: field %d: %v", base+pos, num, protowire.ParseError(n))
+			}
+			pos += n
+			field.Value = fmt.Sprint(v)
+			field.Alternatives = map[string]interface{}{"sfixed64": fmt.Sprint(int64(v)), "double": jsonFloat(math.Float64frombits(v))}
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data[pos:])
+			if n < 0 {
+				return nil, fmt.Errorf("offset %d: field %d: %v", base+pos, num, protowire.ParseError(n))
+			}
+			//the payload starts after its length prefix
+			start := base + pos + n - len(v)
+			pos += n
+			if message, err := decodeProtobufMessage(v, start, depth+1); err == nil && len(message) > 0 {
+				field.Message = message
+				if utf8.Valid(v) {
+					field.Alternatives = map[string]interface{}{"string": string(v)}
+				}
+			} else if utf8.Valid(v) {
+				field.Value = string(v)
+			} else {
+				field.Value = hex.EncodeToString(v)
+			}
+		case protowire.StartGroupType:
+			v, n := protowire.ConsumeGroup(num, data[pos:])
+			if n < 0 {
+				return nil, fmt.Errorf("offset %d: field %d: %v", base+pos, num, protowire.ParseError(n))
+			}
+			message, err := decodeProtobufMessage(v, base+pos, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			pos += n
+			field.Message = message
+		default:
+			return nil, fmt.Errorf("offset %d: field %d: unexpected wire type %d", field.Offset, num, typ)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// jsonFloat keeps NaN and Inf, which encoding/json refuses, as strings
+func jsonFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	return f
+}
+
+// DecodeProtobufTyped decodes data as messageType described by a base64 FileDescriptorSet
+func DecodeProtobufTyped(data []byte, descriptorSet, messageType string) (json.RawMessage, error) {
+	raw, err := base64.StdEncoding.DecodeString(descriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 descriptor_set: %v", err)
+	}
+	var fds descriptorpb.FileDescriptorSet
+	if err = proto.Unmarshal(raw, &fds); err != nil {
+		return nil, fmt.Errorf("invalid descriptor_set: %v", err)
+	}
+	files, err := protodesc.NewFiles(&fds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor_set: %v", err)
+	}
+	if messageType == "" {
+		return nil, errors.New("message_type is required with descriptor_set")
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(messageType))
+	if err != nil {
+		return nil, fmt.Errorf("message_type %s: %v", messageType, err)
+	}
+	messageDesc, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", messageType)
+	}
+	message := dynamicpb.NewMessage(messageDesc)
+	if err = proto.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+	bytez, err := protojson.Marshal(message)
+	return json.RawMessage(bytez), err
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func decodeWireHex(t *testing.T, encoded string) []*ProtobufField {
+	data, _ := hex.DecodeString(encoded)
+	fields, err := DecodeProtobufWire(data)
+	assert.Nil(t, err, encoded)
+	return fields
+}
+
+func TestProtobufWireTypes(t *testing.T) {
+	//varint 150 from the encoding guide, then -1 as int64
+	fields := decodeWireHex(t, "089601"+"10ffffffffffffffffff01")
+	assert.Equal(t, &ProtobufField{Offset: 0, Field: 1, WireType: "varint", Value: "150",
+		Alternatives: map[string]interface{}{"sint": "75"}}, fields[0])
+	assert.Equal(t, &ProtobufField{Offset: 3, Field: 2, WireType: "varint", Value: "18446744073709551615",
+		Alternatives: map[string]interface{}{"sint": "-9223372036854775808", "int": "-1"}}, fields[1])
+
+	//1.0 as float and as double
+	fields = decodeWireHex(t, "1d0000803f"+"21000000000000f03f")
+	assert.Equal(t, &ProtobufField{Offset: 0, Field: 3, WireType: "fixed32", Value: "1065353216",
+		Alternatives: map[string]interface{}{"sfixed32": "1065353216", "float": 1.0}}, fields[0])
+	assert.Equal(t, &ProtobufField{Offset: 5, Field: 4, WireType: "fixed64", Value: "4607182418800017408",
+		Alternatives: map[string]interface{}{"sfixed64": "4607182418800017408", "double": 1.0}}, fields[1])
+
+	fields = decodeWireHex(t, "2a0474657374"+"3202fffe")
+	assert.Equal(t, &ProtobufField{Offset: 0, Field: 5, WireType: "bytes", Value: "test"}, fields[0])
+	assert.Equal(t, &ProtobufField{Offset: 6, Field: 6, WireType: "bytes", Value: "fffe"}, fields[1])
+}
+
+func TestProtobufNestedMessage(t *testing.T) {
+	//"testing" from the encoding guide, its first byte reads as an end group tag
+	fields := decodeWireHex(t, "120774657374696e67")
+	assert.Equal(t, "testing", fields[0].Value)
+	assert.Nil(t, fields[0].Message)
+
+	//Test3{c: Test1{a: 150}}, the payload is not utf-8
+	fields = decodeWireHex(t, "1a03089601")
+	assert.Nil(t, fields[0].Value)
+	assert.Nil(t, fields[0].Alternatives)
+	assert.Equal(t, []*ProtobufField{{Offset: 2, Field: 1, WireType: "varint", Value: "150",
+		Alternatives: map[string]interface{}{"sint": "75"}}}, fields[0].Message)
+
+	//"\x08\x01" is both a message and a string
+	fields = decodeWireHex(t, "0a020801")
+	assert.Equal(t, "1", fields[0].Message[0].Value)
+	assert.Equal(t, map[string]interface{}{"string": "\x08\x01"}, fields[0].Alternatives)
+}
+
+func TestProtobufGroup(t *testing.T) {
+	//field 4 group holding field 1 = 1, then field 5 = 2
+	fields := decodeWireHex(t, "23"+"0801"+"24"+"2802")
+	assert.Equal(t, 2, len(fields))
+	assert.Equal(t, &ProtobufField{Offset: 0, Field: 4, WireType: "group", Message: []*ProtobufField{
+		{Offset: 1, Field: 1, WireType: "varint", Value: "1", Alternatives: map[string]interface{}{"sint": "-1"}},
+	}}, fields[0])
+	assert.Equal(t, 4, fields[1].Offset)
+}
+
+func TestProtobufWireErrors(t *testing.T) {
+	for encoded, err := range map[string]string{
+		"0896":       "offset 1: field 1:",
+		"1d0000":     "offset 1: field 3:",
+		"0a05616263": "offset 1: field 1:",
+		"230801":     "offset 1: field 4:",
+		"0f":         "offset 0: field 1: unexpected wire type 7",
+		"00":         "offset 0: invalid tag:",
+	} {
+		data, _ := hex.DecodeString(encoded)
+		_, e := DecodeProtobufWire(data)
+		assert.ErrorContains(t, e, err, encoded)
+	}
+}
+
+func TestProtobufDescriptorSet(t *testing.T) {
+	fds := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto),
+	}}
+	raw, err := proto.Marshal(fds)
+	assert.Nil(t, err)
+	descriptorSet := base64.StdEncoding.EncodeToString(raw)
+
+	data, err := proto.Marshal(&timestamppb.Timestamp{Seconds: 1700000000, Nanos: 500000000})
+	assert.Nil(t, err)
+	decoded, err := CodecContent(&CodecBody{Method: "protobuf_decode", Content: hex.EncodeToString(data),
+		DescriptorSet: descriptorSet, MessageType: "google.protobuf.Timestamp"})
+	assert.Nil(t, err)
+	bytez, _ := json.Marshal(decoded)
+	assert.Equal(t, `"2023-11-14T22:13:20.500Z"`, string(bytez))
+
+	_, err = DecodeProtobufTyped(data, descriptorSet, "google.protobuf.Duration")
+	assert.ErrorContains(t, err, "message_type google.protobuf.Duration:")
+	_, err = DecodeProtobufTyped(data, descriptorSet, "")
+	assert.EqualError(t, err, "message_type is required with descriptor_set")
+	_, err = DecodeProtobufTyped(data, "!", "google.protobuf.Timestamp")
+	assert.ErrorContains(t, err, "invalid base64 descriptor_set")
+	_, err = DecodeProtobufTyped([]byte{0x08}, descriptorSet, "google.protobuf.Timestamp")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const MaxRLPDepth = 256
+
+// RLPItem annotates one decoded element with where it sits in the input
+type RLPItem struct {
+	Offset int        `json:"offset"`
+	Kind   string     `json:"kind"`
+	Prefix string     `json:"prefix"`
+	Length int        `json:"length"`
+	Value  string     `json:"value,omitempty"`
+	Items  []*RLPItem `json:"items,omitempty"`
+}
+
+type RLPDecodeResult struct {
+	Value       interface{} `json:"value"`
+	Annotations *RLPItem    `json:"annotations"`
+}
+
+// RLPEncode encodes a JSON value made of hex strings and nested arrays
+func RLPEncode(value json.RawMessage) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return nil, fmt.Errorf("invalid rlp value: %v", err)
+	}
+	item, err := rlpValue(v, "$", 0)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(item)
+}
+
+func rlpValue(v interface{}, path string, depth int) (interface{}, error) {
+	if depth > MaxRLPDepth {
+		return nil, fmt.Errorf("%s: nested deeper than %d", path, MaxRLPDepth)
+	}
+	switch v := v.(type) {
+	case string:
+		b, err := hexutil.Decode(withHexPrefix(v))
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid hex %q: %v", path, v, err)
+		}
+		return b, nil
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, elem := range v {
+			item, err := rlpValue(elem, fmt.Sprintf("%s[%d]", path, i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%s: want a hex string or an array, got %T", path, v)
+}
+
+func withHexPrefix(s string) string {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return s
+	}
+	return "0x" + s
+}
+
+func RLPDecode(data []byte) (*RLPDecodeResult, error) {
+	value, item, rest, err := rlpDecodeItem(data, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("offset %d: %d trailing bytes after the top-level item", len(data)-len(rest), len(rest))
+	}
+	return &RLPDecodeResult{Value: value, Annotations: item}, nil
+}
+
+func rlpDecodeItem(b []byte, offset, depth int) (value interface{}, item *RLPItem, rest []byte, err error) {
+	if depth > MaxRLPDepth {
+		return nil, nil, nil, fmt.Errorf("offset %d: nested deeper than %d", offset, MaxRLPDepth)
+	}
+	if len(b) == 0 {
+		return nil, nil, nil, fmt.Errorf("offset %d: empty input", offset)
+	}
+	kind, content, rest, err := rlp.Split(b)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("offset %d (%s): %v", offset, rlpHeaderNote(b), err)
+	}
+	headerLen := len(b) - len(content) - len(rest)
+	item = &RLPItem{
+		Offset: offset,
+		Prefix: hexutil.Encode(b[:headerLen]),
+		Length: len(content),
+	}
+	switch kind {
+	case rlp.Byte, rlp.String:
+		item.Kind = "string"
+		if kind == rlp.Byte {
+			item.Kind = "byte"
+		}
+		item.Value = hexutil.Encode(content)
+		return item.Value, item, rest, nil
+	}
+	item.Kind = "list"
+	values := []interface{}{}
+	item.Items = []*RLPItem{}
+	for elemOffset := offset + headerLen; len(content) > 0; {
+		value, elem, remain, err := rlpDecodeItem(content, elemOffset, depth+1)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		elemOffset += len(content) - len(remain)
+		content = remain
+		values = append(values, value)
+		item.Items = append(item.Items, elem)
+	}
+	return values, item, rest, nil
+}
+
+// rlpHeaderNote describes what the prefix at b[0] declares, for error messages
+func rlpHeaderNote(b []byte) string {
+	prefix := b[0]
+	available := len(b) - 1
+	var kind string
+	var sizeLen, size int
+	switch {
+	case prefix < 0x80:
+		return fmt.Sprintf("prefix 0x%02x, single byte", prefix)
+	case prefix <= 0xb7:
+		kind, size = "string", int(prefix-0x80)
+	case prefix <= 0xbf:
+		kind, sizeLen = "string", int(prefix-0xb7)
+	case prefix <= 0xf7:
+		kind, size = "list", int(prefix-0xc0)
+	default:
+		kind, sizeLen = "list", int(prefix-0xf7)
+	}
+	if sizeLen > 0 {
+		if sizeLen > available {
+			return fmt.Sprintf("prefix 0x%02x, %s with %d length bytes, %d available", prefix, kind, sizeLen, available)
+		}
+		for _, c := range b[1 : 1+sizeLen] {
+			size = size<<8 | int(c)
+		}
+		available -= sizeLen
+	}
+	return fmt.Sprintf("prefix 0x%02x, %s of %d bytes, %d available", prefix, kind, size, available)
+}
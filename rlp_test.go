@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const loremIpsum = "Lorem ipsum dolor sit amet, consectetur adipisicing elit"
+
+// vectors of the Ethereum RLP specification
+func TestRLPVectors(t *testing.T) {
+	lorem := hex.EncodeToString([]byte(loremIpsum))
+	tests := []struct {
+		value   string
+		encoded string
+	}{
+		{`"0x646f67"`, "83646f67"},
+		{`["0x636174", "0x646f67"]`, "c88363617483646f67"},
+		{`""`, "80"},
+		{`[]`, "c0"},
+		{`"0x00"`, "00"},
+		{`"0x0f"`, "0f"},
+		{`"0x0400"`, "820400"},
+		{`[[], [[]], [[], [[]]]]`, "c7c0c1c0c3c0c1c0"},
+		{`"` + lorem + `"`, "b838" + lorem},
+		{`["` + lorem + `"]`, "f83ab838" + lorem},
+	}
+	for _, test := range tests {
+		encoded, err := RLPEncode(json.RawMessage(test.value))
+		assert.Nil(t, err, test.value)
+		assert.Equal(t, test.encoded, hex.EncodeToString(encoded), test.value)
+
+		data, _ := hex.DecodeString(test.encoded)
+		decoded, err := RLPDecode(data)
+		if !assert.Nil(t, err, test.encoded) {
+			continue
+		}
+		// decoded values are 0x-prefixed, the empty string included
+		roundTrip, err := RLPEncode(mustJSON(decoded.Value))
+		assert.Nil(t, err)
+		assert.Equal(t, test.encoded, hex.EncodeToString(roundTrip))
+	}
+}
+
+func mustJSON(v interface{}) json.RawMessage {
+	bytez, _ := json.Marshal(v)
+	return bytez
+}
+
+func TestRLPAnnotations(t *testing.T) {
+	data, _ := hex.DecodeString("c88363617483646f67")
+	decoded, err := RLPDecode(data)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"0x636174", "0x646f67"}, decoded.Value)
+	assert.Equal(t, &RLPItem{Offset: 0, Kind: "list", Prefix: "0xc8", Length: 8, Items: []*RLPItem{
+		{Offset: 1, Kind: "string", Prefix: "0x83", Length: 3, Value: "0x636174"},
+		{Offset: 5, Kind: "string", Prefix: "0x83", Length: 3, Value: "0x646f67"},
+	}}, decoded.Annotations)
+
+	// a long list, a long string inside it and a single byte after it
+	data, _ = hex.DecodeString("f83bb838" + hex.EncodeToString([]byte(loremIpsum)) + "01")
+	decoded, err = RLPDecode(data)
+	assert.Nil(t, err)
+	root := decoded.Annotations
+	assert.Equal(t, "0xf83b", root.Prefix)
+	assert.Equal(t, 59, root.Length)
+	assert.Equal(t, 2, root.Items[0].Offset)
+	assert.Equal(t, "0xb838", root.Items[0].Prefix)
+	assert.Equal(t, 56, root.Items[0].Length)
+	assert.Equal(t, &RLPItem{Offset: 60, Kind: "byte", Prefix: "0x", Length: 1, Value: "0x01"}, root.Items[1])
+}
+
+func TestRLPDecodeErrors(t *testing.T) {
+	tests := []struct {
+		encoded string
+		err     string
+	}{
+		{"", "offset 0: empty input"},
+		{"83646f6700", "offset 4: 1 trailing bytes after the top-level item"},
+		{"83646f", "offset 0 (prefix 0x83, string of 3 bytes, 2 available)"},
+		{"b901", "offset 0 (prefix 0xb9, string with 2 length bytes, 1 available)"},
+		{"f90100", "offset 0 (prefix 0xf9, list of 256 bytes, 0 available)"},
+		{"c483646f", "offset 0 (prefix 0xc4, list of 4 bytes, 3 available)"},
+		{"c4c283646f", "offset 2 (prefix 0x83, string of 3 bytes, 1 available)"},
+		// a single byte below 0x80 must not carry a string prefix
+		{"8100", "offset 0 (prefix 0x81, string of 1 bytes, 1 available)"},
+		{"c3018101", "offset 2 (prefix 0x81, string of 1 bytes, 1 available)"},
+		// a length that fits the short form must not use the long one
+		{"b80161", "offset 0 (prefix 0xb8, string of 1 bytes, 1 available)"},
+	}
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.encoded)
+		_, err := RLPDecode(data)
+		if assert.NotNil(t, err, test.encoded) {
+			assert.True(t, strings.HasPrefix(err.Error(), test.err), "%s: %v", test.encoded, err)
+		}
+	}
+}
+
+func TestRLPEncodeErrors(t *testing.T) {
+	for value, err := range map[string]string{
+		`["0x01", "zz"]`: "$[1]: invalid hex",
+		`[["0x01", 1]]`:  "$[0][1]: want a hex string or an array",
+		`{"a": "0x01"}`:  "$: want a hex string or an array",
+		`"0x0"`:          "$: invalid hex",
+		`[`:              "invalid rlp value",
+		strings.Repeat("[", MaxRLPDepth+2) + strings.Repeat("]", MaxRLPDepth+2): "nested deeper than",
+	} {
+		_, e := RLPEncode(json.RawMessage(value))
+		assert.ErrorContains(t, e, err, value)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// ecdsaSignature is the ASN.1 SEQUENCE { r INTEGER, s INTEGER } of DER signatures
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+type Secp256k1Signature struct {
+	R *big.Int
+	S *big.Int
+	// V is the recovery id 0 or 1, -1 when the format doesn't carry it
+	V int
+}
+
+type SignResult struct {
+	Digest string `json:"digest"`
+	//r||s||v, v is the recovery id 0 or 1
+	Signature string `json:"signature"`
+	Der       string `json:"der"`
+	//r||s
+	Compact string `json:"compact"`
+	V       int    `json:"v"`
+}
+
+type VerifyResult struct {
+	Valid bool `json:"valid"`
+	//high-S signatures still verify but are malleable
+	LowS bool `json:"low_s"`
+}
+
+// SignDigest is the digest to sign or verify: message itself (hex by default) without hash,
+// otherwise hash of message (utf8 by default)
+func (ab *AsymmetricBody) SignDigest(size int) ([]byte, error) {
+	if ab.Hash == "" {
+		digest, err := DecodeContent("message", strings.TrimPrefix(ab.Message, "0x"), ab.MessageEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		if size > 0 && len(digest) != size {
+			return nil, fmt.Errorf("invalid digest length: %d, want %d bytes or set hash", len(digest), size)
+		}
+		return digest, nil
+	}
+	message, err := DecodeContent("message", ab.Message, ab.MessageEncoding, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := HashSum(ab.Hash, message)
+	if err != nil {
+		return nil, err
+	}
+	if size > 0 && len(digest) != size {
+		return nil, fmt.Errorf("%s digest is %d bytes, want %d", ab.Hash, len(digest), size)
+	}
+	return digest, nil
+}
+
+func decodeHex(name, str string) ([]byte, error) {
+	return DecodeContent(name, strings.TrimPrefix(strings.TrimSpace(str), "0x"), EncodingHex, EncodingHex)
+}
+
+// ParseSecp256k1Signature reads format rsv, compact or der. When format is empty 65 and 64 bytes
+// are rsv and compact, which a DER header can't rule out, and anything else is der
+func ParseSecp256k1Signature(sig []byte, format string) (*Secp256k1Signature, error) {
+	if format == "" {
+		switch len(sig) {
+		case 65:
+			format = "rsv"
+		case 64:
+			format = "compact"
+		default:
+			format = "der"
+		}
+	}
+	result := &Secp256k1Signature{V: -1}
+	switch format {
+	case "rsv", "compact":
+		if len(sig) != 64 && !(format == "rsv" && len(sig) == 65) {
+			return nil, fmt.Errorf("invalid %s signature length: %d", format, len(sig))
+		}
+		result.R = new(big.Int).SetBytes(sig[:32])
+		result.S = new(big.Int).SetBytes(sig[32:64])
+		if len(sig) == 65 {
+			// 27/28 is the Ethereum and Bitcoin message convention for 0/1
+			v := int(sig[64])
+			if v >= 27 {
+				v -= 27
+			}
+			if v != 0 && v != 1 {
+				return nil, fmt.Errorf("invalid recovery id: %d", sig[64])
+			}
+			result.V = v
+		}
+	case "der":
+		var der ecdsaSignature
+		rest, err := asn1.Unmarshal(sig, &der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid der signature: %v", err)
+		}
+		if len(rest) > 0 {
+			return nil, fmt.Errorf("invalid der signature: %d trailing bytes", len(rest))
+		}
+		result.R, result.S = der.R, der.S
+	default:
+		return nil, fmt.Errorf("invalid signature format: %s, want rsv, der or compact", format)
+	}
+	if result.R.Sign() <= 0 || result.R.Cmp(secp256k1N) >= 0 || result.S.Sign() <= 0 || result.S.Cmp(secp256k1N) >= 0 {
+		return nil, errors.New("invalid signature: r or s out of range")
+	}
+	return result, nil
+}
+
+func (sig *Secp256k1Signature) LowS() bool {
+	return sig.S.Cmp(secp256k1HalfN) <= 0
+}
+
+// Normalize replaces s with n-s when s is high, which also flips the recovery id
+func (sig *Secp256k1Signature) Normalize() {
+	if sig.LowS() {
+		return
+	}
+	sig.S = new(big.Int).Sub(secp256k1N, sig.S)
+	if sig.V >= 0 {
+		sig.V ^= 1
+	}
+}
+
+func (sig *Secp256k1Signature) Compact() []byte {
+	compact := make([]byte, 64)
+	sig.R.FillBytes(compact[:32])
+	sig.S.FillBytes(compact[32:])
+	return compact
+}
+
+func (sig *Secp256k1Signature) RSV() []byte {
+	return append(sig.Compact(), byte(sig.V))
+}
+
+func (sig *Secp256k1Signature) DER() []byte {
+	der, _ := asn1.Marshal(ecdsaSignature{R: sig.R, S: sig.S})
+	return der
+}
+
+func ParseSecp256k1PublicKey(pub []byte) (*ecdsa.PublicKey, error) {
+	if len(pub) == 33 {
+		return crypto.DecompressPubkey(pub)
+	}
+	if len(pub) == 64 {
+		pub = append([]byte{0x04}, pub...)
+	}
+	return crypto.UnmarshalPubkey(pub)
+}
+
+func Secp256k1Sign(privkey *ecdsa.PrivateKey, digest []byte) (*Secp256k1Signature, error) {
+	rsv, err := crypto.Sign(digest, privkey)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ParseSecp256k1Signature(rsv, "rsv")
+	if err != nil {
+		return nil, err
+	}
+	sig.Normalize()
+	return sig, nil
+}
+
+func Secp256k1Verify(pub *ecdsa.PublicKey, digest []byte, sig *Secp256k1Signature) *VerifyResult {
+	result := &VerifyResult{LowS: sig.LowS()}
+	normalized := *sig
+	normalized.Normalize()
+	result.Valid = crypto.VerifySignature(crypto.FromECDSAPub(pub), digest, normalized.Compact())
+	return result
+}
+
+func Secp256k1Recover(digest []byte, sig *Secp256k1Signature) (*ecdsa.PublicKey, error) {
+	if sig.V < 0 {
+		return nil, errors.New("recover needs a 65-byte r||s||v signature")
+	}
+	normalized := *sig
+	normalized.Normalize()
+	return crypto.SigToPub(digest, normalized.RSV())
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// web3.eth.accounts.sign("Some data", key) of the web3.js documentation
+const (
+	secp256k1TestKey     = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	secp256k1TestAddress = "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+	secp256k1TestDigest  = "1da44b586eb0729ff70a73c326926f6ed5a25f5b056e7f47fbc6e58d86871655"
+	secp256k1TestR       = "b91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd"
+	secp256k1TestS       = "6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a029"
+)
+
+// highS returns r || n-s, the malleated twin of the test signature
+func highS() string {
+	s, _ := new(big.Int).SetString(secp256k1TestS, 16)
+	return secp256k1TestR + hex.EncodeToString(new(big.Int).Sub(secp256k1N, s).FillBytes(make([]byte, 32)))
+}
+
+func TestSecp256k1Sign(t *testing.T) {
+	result, err := Secp256k1Content(&AsymmetricBody{Operation: "sign", Content: secp256k1TestKey, Message: "0x" + secp256k1TestDigest})
+	assert.Nil(t, err)
+	sig := result.(*SignResult)
+	assert.Equal(t, secp256k1TestDigest, sig.Digest)
+	assert.Equal(t, secp256k1TestR+secp256k1TestS+"01", sig.Signature)
+	assert.Equal(t, secp256k1TestR+secp256k1TestS, sig.Compact)
+	// r has the high bit set, so DER pads it with a zero byte
+	assert.Equal(t, "3045022100"+secp256k1TestR+"0220"+secp256k1TestS, sig.Der)
+	assert.Equal(t, 1, sig.V)
+
+	// the same digest from the message with the Ethereum prefix
+	result, err = Secp256k1Content(&AsymmetricBody{Operation: "sign", Content: secp256k1TestKey, Hash: "keccak256", Message: "\x19Ethereum Signed Message:\n9Some data"})
+	assert.Nil(t, err)
+	assert.Equal(t, secp256k1TestDigest, result.(*SignResult).Digest)
+}
+
+func TestSecp256k1Normalize(t *testing.T) {
+	high := highS()
+	for _, v := range []string{"00", "1b"} {
+		raw, _ := hex.DecodeString(high + v)
+		sig, err := ParseSecp256k1Signature(raw, "")
+		assert.Nil(t, err)
+		assert.False(t, sig.LowS())
+		assert.Equal(t, 0, sig.V)
+		sig.Normalize()
+		assert.True(t, sig.LowS())
+		assert.Equal(t, 1, sig.V)
+		assert.Equal(t, secp256k1TestR+secp256k1TestS+"01", hex.EncodeToString(sig.RSV()))
+	}
+
+	// compact carries no recovery id, which must stay unset
+	raw, _ := hex.DecodeString(high)
+	sig, err := ParseSecp256k1Signature(raw, "compact")
+	assert.Nil(t, err)
+	sig.Normalize()
+	assert.Equal(t, -1, sig.V)
+	assert.Equal(t, secp256k1TestS, hex.EncodeToString(sig.Compact()[32:]))
+}
+
+func TestSecp256k1VerifyForms(t *testing.T) {
+	pub := "044e3b81af9c2234cad09d679ce6035ed1392347ce64ce405f5dcd36228a25de6e47fd35c4215d1edf53e6f83de344615ce719bdb0fd878f6ed76f06dd277956de"
+	sigs := map[string]string{
+		"":        secp256k1TestR + secp256k1TestS + "1c",
+		"rsv":     secp256k1TestR + secp256k1TestS + "01",
+		"compact": secp256k1TestR + secp256k1TestS,
+		"der":     "3045022100" + secp256k1TestR + "0220" + secp256k1TestS,
+	}
+	for format, sig := range sigs {
+		result, err := Secp256k1Content(&AsymmetricBody{Operation: "verify", Pubkey: pub, Signature: "0x" + sig, SignatureFormat: format, Message: secp256k1TestDigest})
+		assert.Nil(t, err, format)
+		assert.Equal(t, &VerifyResult{Valid: true, LowS: true}, result, format)
+	}
+	// DER is detected without signature_format as well, against the compressed key this time
+	compressed := "024e3b81af9c2234cad09d679ce6035ed1392347ce64ce405f5dcd36228a25de6e"
+	result, err := Secp256k1Content(&AsymmetricBody{Operation: "verify", Pubkey: compressed, Signature: sigs["der"], Message: secp256k1TestDigest})
+	assert.Nil(t, err)
+	assert.Equal(t, &VerifyResult{Valid: true, LowS: true}, result)
+
+	// high-S still verifies but is reported malleable
+	result, err = Secp256k1Content(&AsymmetricBody{Operation: "verify", Pubkey: pub, Signature: highS(), Message: secp256k1TestDigest})
+	assert.Nil(t, err)
+	assert.Equal(t, &VerifyResult{Valid: true, LowS: false}, result)
+
+	digest := "00" + secp256k1TestDigest[2:]
+	result, err = Secp256k1Content(&AsymmetricBody{Operation: "verify", Pubkey: pub, Signature: sigs["compact"], Message: digest})
+	assert.Nil(t, err)
+	assert.Equal(t, false, result.(*VerifyResult).Valid)
+}
+
+func TestSecp256k1Recover(t *testing.T) {
+	for _, sig := range []string{secp256k1TestR + secp256k1TestS + "1c", highS() + "1b"} {
+		result, err := Secp256k1Content(&AsymmetricBody{Operation: "recover", Signature: sig, Message: secp256k1TestDigest})
+		if !assert.Nil(t, err) {
+			continue
+		}
+		bytez, _ := json.Marshal(result)
+		assert.Contains(t, string(bytez), `"address":"`+secp256k1TestAddress+`"`)
+	}
+	_, err := Secp256k1Content(&AsymmetricBody{Operation: "recover", Signature: secp256k1TestR + secp256k1TestS, Message: secp256k1TestDigest})
+	assert.NotNil(t, err)
+}
+
+func TestParseSecp256k1SignatureFormat(t *testing.T) {
+	// a 64-byte compact signature whose r happens to look like a DER header
+	raw, _ := hex.DecodeString("303e" + secp256k1TestR[4:] + secp256k1TestS)
+	sig, err := ParseSecp256k1Signature(raw, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "303e"+secp256k1TestR[4:], hex.EncodeToString(sig.R.FillBytes(make([]byte, 32))))
+	assert.Equal(t, -1, sig.V)
+
+	invalid := []struct {
+		sig    string
+		format string
+	}{
+		{secp256k1TestR + secp256k1TestS + "02", ""},
+		{secp256k1TestR + secp256k1TestS + "01", "compact"},
+		{secp256k1TestR, ""},
+		{secp256k1TestR + "00", "rsv"},
+		{"3045022100" + secp256k1TestR + "0220" + secp256k1TestS + "00", "der"},
+		{hex.EncodeToString(secp256k1N.Bytes()) + secp256k1TestS, ""},
+		{secp256k1TestR + secp256k1TestS, "p1363"},
+	}
+	for _, test := range invalid {
+		raw, _ := hex.DecodeString(test.sig)
+		_, err := ParseSecp256k1Signature(raw, test.format)
+		assert.NotNil(t, err, "%s %s", test.sig, test.format)
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/tjfoc/gmsm/sm2"
+	"github.com/tjfoc/gmsm/sm3"
+)
+
+const DefaultSM2UID = "1234567812345678"
+
+func SM2UID(uid string) []byte {
+	if uid == "" {
+		return []byte(DefaultSM2UID)
+	}
+	return []byte(uid)
+}
+
+// accept 04||x||y or raw x||y
+func ParseSM2PublicKey(raw []byte) (*sm2.PublicKey, error) {
+	if len(raw) == 65 && raw[0] == 0x04 {
+		raw = raw[1:]
+	}
+	if len(raw) != 64 {
+		return nil, errors.New("invalid sm2 pubkey: want 64 bytes x||y or 65 bytes 04||x||y")
+	}
+	pub := &sm2.PublicKey{
+		Curve: sm2.P256Sm2(),
+		X:     new(big.Int).SetBytes(raw[:32]),
+		Y:     new(big.Int).SetBytes(raw[32:]),
+	}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("invalid sm2 pubkey: point is not on curve")
+	}
+	return pub, nil
+}
+
+// SM3(ZA || msg), the digest signed by SM2
+func SM2IDDigest(pub *sm2.PublicKey, uid, msg []byte) ([]byte, error) {
+	za, err := sm2.ZA(pub, uid)
+	if err != nil {
+		return nil, err
+	}
+	d := sm3.New()
+	d.Write(za)
+	d.Write(msg)
+	return d.Sum(nil), nil
+}
+
+func ParseSM2PrivateKey(raw []byte) (*sm2.PrivateKey, error) {
+	curve := sm2.P256Sm2()
+	d := new(big.Int).SetBytes(raw)
+	if len(raw) != 32 || d.Sign() == 0 || d.Cmp(new(big.Int).Sub(curve.Params().N, big.NewInt(1))) >= 0 {
+		return nil, errors.New("invalid sm2 privkey: want 32 bytes in [1, n-2]")
+	}
+	priv := &sm2.PrivateKey{D: d}
+	priv.PublicKey.Curve = curve
+	priv.PublicKey.X, priv.PublicKey.Y = curve.ScalarBaseMult(raw)
+	return priv, nil
+}
+
+// 04||x||y
+func SM2PublicKeyBytes(pub *sm2.PublicKey) []byte {
+	raw := make([]byte, 65)
+	raw[0] = 0x04
+	pub.X.FillBytes(raw[1:33])
+	pub.Y.FillBytes(raw[33:])
+	return raw
+}
+
+// last 20 bytes of SM3(x||y)
+func SM2Address(pub *sm2.PublicKey) string {
+	digest := sm3.Sm3Sum(SM2PublicKeyBytes(pub)[1:])
+	return hex.EncodeToString(digest[12:])
+}
+
+func SM2Content(ab *AsymmetricBody) (interface{}, error) {
+	switch ab.Operation {
+	case "generate":
+		priv, err := sm2.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Privkey string `json:"privkey"`
+			Pubkey  string `json:"pubkey"`
+			Address string `json:"address"`
+		}
+		result.Privkey, err = EncodeContent(priv.D.FillBytes(make([]byte, 32)), ab.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		result.Pubkey, _ = EncodeContent(SM2PublicKeyBytes(&priv.PublicKey), ab.OutputEncoding, EncodingHex)
+		result.Address = SM2Address(&priv.PublicKey)
+		return result, nil
+	case "get_address":
+		privBytes, err := DecodeContent("content", ab.Content, ab.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := ParseSM2PrivateKey(privBytes)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Privkey string `json:"privkey"`
+			Pubkey  string `json:"pubkey"`
+			Address string `json:"address"`
+		}
+		result.Privkey = ab.Content
+		result.Pubkey, err = EncodeContent(SM2PublicKeyBytes(&priv.PublicKey), ab.OutputEncoding, EncodingHex)
+		result.Address = SM2Address(&priv.PublicKey)
+		return result, err
+	case "sign":
+		privBytes, err := DecodeContent("content", ab.Content, ab.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := ParseSM2PrivateKey(privBytes)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := DecodeContent("message", ab.Message, ab.MessageEncoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		r, s, err := sm2.Sm2Sign(priv, msg, SM2UID(ab.Uid), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			//r||s
+			Signature string `json:"signature"`
+			Der       string `json:"der"`
+		}
+		raw := make([]byte, 64)
+		r.FillBytes(raw[:32])
+		s.FillBytes(raw[32:])
+		der, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+		if err != nil {
+			return nil, err
+		}
+		result.Signature, err = EncodeContent(raw, ab.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		result.Der, _ = EncodeContent(der, ab.OutputEncoding, EncodingHex)
+		return result, nil
+	case "verify":
+		pubBytes, err := decodeHex("pubkey", ab.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := ParseSM2PublicKey(pubBytes)
+		if err != nil {
+			return nil, err
+		}
+		sigBytes, err := decodeHex("signature", ab.Signature)
+		if err != nil {
+			return nil, err
+		}
+		r, s, err := parseSM2Signature(sigBytes, ab.SignatureFormat)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := DecodeContent("message", ab.Message, ab.MessageEncoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Valid bool `json:"valid"`
+		}
+		result.Valid = sm2.Sm2Verify(pub, msg, SM2UID(ab.Uid), r, s)
+		return result, nil
+	case "encrypt":
+		pubBytes, err := decodeHex("pubkey", ab.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := ParseSM2PublicKey(pubBytes)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := DecodeContent("message", ab.Message, ab.MessageEncoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		if len(msg) == 0 {
+			return nil, errors.New("empty sm2 plaintext")
+		}
+		ciphertext, err := sm2.Encrypt(pub, msg, rand.Reader, sm2.C1C3C2)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err = FormatSM2Ciphertext(ciphertext, ab.Layout)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeContent(ciphertext, ab.OutputEncoding, EncodingHex)
+	case "decrypt":
+		privBytes, err := DecodeContent("content", ab.Content, ab.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := ParseSM2PrivateKey(privBytes)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := decodeHex("ciphertext", ab.Ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err = ParseSM2Ciphertext(ciphertext, ab.Layout)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := sm2.Decrypt(priv, ciphertext, sm2.C1C3C2)
+		if err != nil {
+			return nil, errors.New("sm2 decryption failed: wrong key, layout or corrupted ciphertext")
+		}
+		return EncodeContent(msg, ab.OutputEncoding, EncodingUTF8)
+	case "convert":
+		ciphertext, err := decodeHex("ciphertext", ab.Ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		converted, err := ConvertSM2Ciphertext(ciphertext, ab.Layout, ab.ToLayout)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeContent(converted, ab.OutputEncoding, EncodingHex)
+	case "derive_shared":
+		privBytes, err := DecodeContent("content", ab.Content, ab.InputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := ParseSM2PrivateKey(privBytes)
+		if err != nil {
+			return nil, err
+		}
+		ephemeralBytes, err := decodeHex("ephemeral_key", ab.EphemeralKey)
+		if err != nil {
+			return nil, err
+		}
+		ephemeral, err := ParseSM2PrivateKey(ephemeralBytes)
+		if err != nil {
+			return nil, err
+		}
+		peerPubBytes, err := decodeHex("pubkey", ab.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		peerPub, err := ParseSM2PublicKey(peerPubBytes)
+		if err != nil {
+			return nil, err
+		}
+		peerEphemeralBytes, err := decodeHex("peer_ephemeral_pubkey", ab.PeerEphemeralPubkey)
+		if err != nil {
+			return nil, err
+		}
+		peerEphemeral, err := ParseSM2PublicKey(peerEphemeralBytes)
+		if err != nil {
+			return nil, err
+		}
+		if ab.Role != "initiator" && ab.Role != "responder" {
+			return nil, fmt.Errorf("invalid sm2 key exchange role: %s, want initiator or responder", ab.Role)
+		}
+		keyLen := intOrDefault(ab.KeyLen, DefaultKdfKeyLen)
+		if keyLen <= 0 || keyLen > MaxKdfKeyLen {
+			return nil, fmt.Errorf("key_len must be in [1, %d]", MaxKdfKeyLen)
+		}
+		key, confirmation, peerConfirmation, err := SM2KeyExchange(ab.Role == "initiator", keyLen,
+			priv, ephemeral, peerPub, peerEphemeral, SM2UID(ab.Uid), SM2UID(ab.PeerUid))
+		if err != nil {
+			return nil, err
+		}
+		result := &SM2KeyExchangeResult{}
+		result.Key, err = EncodeContent(key, ab.OutputEncoding, EncodingHex)
+		if err != nil {
+			return nil, err
+		}
+		result.Confirmation, _ = EncodeContent(confirmation, ab.OutputEncoding, EncodingHex)
+		result.PeerConfirmation, _ = EncodeContent(peerConfirmation, ab.OutputEncoding, EncodingHex)
+		return result, nil
+	}
+	return nil, fmt.Errorf("invalid asymmetric operation: %s", ab.Operation)
+}
+
+// raw r||s or der, detected from the length when format is empty
+func parseSM2Signature(sig []byte, format string) (r, s *big.Int, err error) {
+	if format == "" {
+		format = "der"
+		if len(sig) == 64 {
+			format = "raw"
+		}
+	}
+	switch format {
+	case "raw":
+		if len(sig) != 64 {
+			return nil, nil, fmt.Errorf("invalid raw signature length: %d", len(sig))
+		}
+		return new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]), nil
+	case "der":
+		var der ecdsaSignature
+		rest, err := asn1.Unmarshal(sig, &der)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid der signature: %v", err)
+		}
+		if len(rest) > 0 {
+			return nil, nil, fmt.Errorf("invalid der signature: %d trailing bytes", len(rest))
+		}
+		return der.R, der.S, nil
+	}
+	return nil, nil, fmt.Errorf("invalid signature format: %s, want raw or der", format)
+}
+
+// SM2KDF is the GM/T 0003.4 key derivation: SM3(z||ct) blocks with a 32-bit big-endian counter from 1
+func SM2KDF(keyLen int, z ...[]byte) []byte {
+	key := make([]byte, 0, keyLen+32)
+	for ct := uint32(1); len(key) < keyLen; ct++ {
+		d := sm3.New()
+		for _, b := range z {
+			d.Write(b)
+		}
+		d.Write([]byte{byte(ct >> 24), byte(ct >> 16), byte(ct >> 8), byte(ct)})
+		key = append(key, d.Sum(nil)...)
+	}
+	return key[:keyLen]
+}
+
+type SM2KeyExchangeResult struct {
+	Key string `json:"key"`
+	//send to the peer: SA for the initiator, SB for the responder
+	Confirmation string `json:"confirmation"`
+	//compare with the peer's: SB (S1) for the initiator, SA (S2) for the responder
+	PeerConfirmation string `json:"peer_confirmation"`
+}
+
+// sm2XHat is x̄ = 2^w + (x & (2^w - 1)) with w = 127
+func sm2XHat(x *big.Int) *big.Int {
+	w := new(big.Int).Lsh(big.NewInt(1), 127)
+	return new(big.Int).Add(w, new(big.Int).And(x, new(big.Int).Sub(w, big.NewInt(1))))
+}
+
+func sm2Coordinate(v *big.Int) []byte {
+	return v.FillBytes(make([]byte, 32))
+}
+
+// SM2KeyExchange is the GM/T 0003.3 key agreement of one side: priv and ephemeral are this side's
+// static and temporary keys, uid and peerUID the identities behind ZA and ZB
+func SM2KeyExchange(initiator bool, keyLen int, priv, ephemeral *sm2.PrivateKey, peerPub, peerEphemeral *sm2.PublicKey, uid, peerUID []byte) (key, confirmation, peerConfirmation []byte, err error) {
+	curve := sm2.P256Sm2()
+	n := curve.Params().N
+	// t = (d + x̄·r) mod n, U = t·(P_peer + x̄_peer·R_peer)
+	t := new(big.Int).Mul(sm2XHat(ephemeral.PublicKey.X), ephemeral.D)
+	t.Add(t, priv.D).Mod(t, n)
+	x, y := curve.ScalarMult(peerEphemeral.X, peerEphemeral.Y, sm2XHat(peerEphemeral.X).Bytes())
+	x, y = curve.Add(peerPub.X, peerPub.Y, x, y)
+	x, y = curve.ScalarMult(x, y, t.Bytes())
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, nil, nil, errors.New("sm2 key exchange: shared point is at infinity")
+	}
+	za, err := sm2.ZA(&priv.PublicKey, uid)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	zb, err := sm2.ZA(peerPub, peerUID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ra, rb := &ephemeral.PublicKey, peerEphemeral
+	if !initiator {
+		za, zb = zb, za
+		ra, rb = rb, ra
+	}
+	xu, yu := sm2Coordinate(x), sm2Coordinate(y)
+	key = SM2KDF(keyLen, xu, yu, za, zb)
+	inner := sm3.New()
+	for _, b := range [][]byte{xu, za, zb, sm2Coordinate(ra.X), sm2Coordinate(ra.Y), sm2Coordinate(rb.X), sm2Coordinate(rb.Y)} {
+		inner.Write(b)
+	}
+	innerHash := inner.Sum(nil)
+	s02 := sm3.Sm3Sum(append(append([]byte{0x02}, yu...), innerHash...))
+	s03 := sm3.Sm3Sum(append(append([]byte{0x03}, yu...), innerHash...))
+	if initiator {
+		return key, s03, s02, nil
+	}
+	return key, s02, s03, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vectors of emmansun/gmsm kdf_test.go, the old case is x2||y2 of a GM/T 0003.4 encryption
+func TestSM2KDF(t *testing.T) {
+	emmansun := "708993ef1388a0ae4245a19bb6c02554c632633e356ddb989beb804fda96cfd47eba4fa460e7b277bc6b4ce4d07ed493"
+	for _, keyLen := range []int{16, 32, 48} {
+		assert.Equal(t, emmansun[:2*keyLen], hex.EncodeToString(SM2KDF(keyLen, []byte("emmansun"))))
+	}
+	z, _ := hex.DecodeString("64D20D27D0632957F8028C1E024F6B02EDF23102A566C932AE8BD613A8E865FE" +
+		"58D225ECA784AE300A81A2D48281A828E1CEDF11C4219099840265375077BF78")
+	assert.Equal(t, "006e30dae231b071dfad8aa379e90264491603", hex.EncodeToString(SM2KDF(19, z)))
+	// z may come in pieces
+	assert.Equal(t, "006e30dae231b071dfad8aa379e90264491603", hex.EncodeToString(SM2KDF(19, z[:32], z[32:])))
+
+	// every length is a prefix of a longer one, across the 32-byte block boundaries
+	long := SM2KDF(100, []byte("emmansun"))
+	for keyLen := 1; keyLen <= len(long); keyLen++ {
+		assert.Equal(t, long[:keyLen], SM2KDF(keyLen, []byte("emmansun")), "key_len %d", keyLen)
+	}
+}
+
+func sm2PubkeyOf(t *testing.T, privHex string) string {
+	raw, _ := hex.DecodeString(privHex)
+	priv, err := ParseSM2PrivateKey(raw)
+	assert.Nil(t, err)
+	return hex.EncodeToString(SM2PublicKeyBytes(&priv.PublicKey))
+}
+
+// the key exchange vectors of emmansun/gmsm sm2_keyexchange_test.go (uid Alice and Bob, 48-byte keys),
+// SA and SB come from its KeyExchange run on the same ephemeral keys
+func TestSM2KeyExchange(t *testing.T) {
+	vectors := []struct {
+		initiator, initiatorEphemeral string
+		responder, responderEphemeral string
+		key, sa, sb                   string
+	}{
+		{
+			"e04c3fd77408b56a648ad439f673511a2ae248def3bab26bdfc9cdbd0ae9607e",
+			"6fe0bac5b09d3ab10f724638811c34464790520e4604e71e6cb0e5310623b5b1",
+			"7a1136f60d2c5531447e5a3093078c2a505abf74f33aefed927ac0a5b27e7dd7",
+			"d0233bdbb0b8a7bfe1aab66132ef06fc4efaedd5d5000692bc21185242a31f6f",
+			"1ad809ebc56ddda532020c352e1e60b121ebeb7b4e632db4dd90a362cf844f8bba85140e30984ddb581199bf5a9dda22",
+			"822dad808df9f31f6e1667ad372689f1b499841ceb36c4fa68c2ea69e9edfd88",
+			"0227e5336ba948ff95372ce925c4d8ebdf1384ddf563374351e0053b5fbadc87",
+		},
+		{
+			"cb5ac204b38d0e5c9fc38a467075986754018f7dbb7cbbc5b4c78d56a88a8ad8",
+			"1681a66c02b67fdadfc53cba9b417b9499d0159435c86bb8760c3a03ae157539",
+			"4f54b10e0d8e9e2fe5cc79893e37fd0fd990762d1372197ed92dde464b2773ef",
+			"a2fe43dea141e9acc88226eaba8908ad17e81376c92102cb8186e8fef61a8700",
+			"7a103ae61a30ed9df573a5febb35a9609cbed5681bcb98a8545351bf7d6824cc4635df5203712ea506e2e3c4ec9b12e7",
+			"c036bc3b1d403478d9e31f045dc30fd6504913b73c6b9a9da756f5851f0b9a43",
+			"14fbc6203755cf19303b10bfb7af1a92cd6e2be354b4510c83434af7523e8fc4",
+		},
+	}
+	for i, v := range vectors {
+		initiator := &AsymmetricBody{Operation: "derive_shared", Role: "initiator", KeyLen: 48,
+			Content: v.initiator, EphemeralKey: v.initiatorEphemeral, Uid: "Alice", PeerUid: "Bob",
+			Pubkey: sm2PubkeyOf(t, v.responder), PeerEphemeralPubkey: sm2PubkeyOf(t, v.responderEphemeral)}
+		responder := &AsymmetricBody{Operation: "derive_shared", Role: "responder", KeyLen: 48,
+			Content: v.responder, EphemeralKey: v.responderEphemeral, Uid: "Bob", PeerUid: "Alice",
+			Pubkey: sm2PubkeyOf(t, v.initiator), PeerEphemeralPubkey: sm2PubkeyOf(t, v.initiatorEphemeral)}
+
+		result, err := SM2Content(initiator)
+		assert.Nil(t, err)
+		assert.Equal(t, &SM2KeyExchangeResult{Key: v.key, Confirmation: v.sa, PeerConfirmation: v.sb}, result, "vector %d initiator", i)
+
+		result, err = SM2Content(responder)
+		assert.Nil(t, err)
+		assert.Equal(t, &SM2KeyExchangeResult{Key: v.key, Confirmation: v.sb, PeerConfirmation: v.sa}, result, "vector %d responder", i)
+
+		// confirmation is optional, a side that skips it still derives the same key, of any length
+		for _, keyLen := range []int{1, 16, 33, 100} {
+			initiator.KeyLen, responder.KeyLen = keyLen, keyLen
+			a, err := SM2Content(initiator)
+			assert.Nil(t, err)
+			b, err := SM2Content(responder)
+			assert.Nil(t, err)
+			assert.Equal(t, a.(*SM2KeyExchangeResult).Key, b.(*SM2KeyExchangeResult).Key, "key_len %d", keyLen)
+			assert.Equal(t, 2*keyLen, len(a.(*SM2KeyExchangeResult).Key))
+			if keyLen <= 48 {
+				assert.Equal(t, v.key[:2*keyLen], a.(*SM2KeyExchangeResult).Key)
+			}
+		}
+		initiator.KeyLen, responder.KeyLen = 48, 48
+
+		// a wrong peer uid changes ZB, so both the key and the confirmations disagree
+		initiator.PeerUid = "Carol"
+		result, err = SM2Content(initiator)
+		assert.Nil(t, err)
+		assert.NotEqual(t, v.key, result.(*SM2KeyExchangeResult).Key)
+		assert.NotEqual(t, v.sa, result.(*SM2KeyExchangeResult).Confirmation)
+	}
+
+	v := vectors[0]
+	invalid := []*AsymmetricBody{
+		{Role: "", Content: v.initiator, EphemeralKey: v.initiatorEphemeral},
+		{Role: "initiator", Content: v.initiator, EphemeralKey: v.initiatorEphemeral, KeyLen: MaxKdfKeyLen + 1},
+		{Role: "initiator", Content: v.initiator},
+		{Role: "initiator", Content: v.initiator, EphemeralKey: v.initiatorEphemeral, PeerEphemeralPubkey: "04" + v.responder + v.responder},
+	}
+	for i, ab := range invalid {
+		ab.Operation = "derive_shared"
+		if ab.Pubkey == "" {
+			ab.Pubkey = sm2PubkeyOf(t, v.responder)
+		}
+		if ab.PeerEphemeralPubkey == "" {
+			ab.PeerEphemeralPubkey = sm2PubkeyOf(t, v.responderEphemeral)
+		}
+		_, err := SM2Content(ab)
+		assert.NotNil(t, err, "invalid %d", i)
+	}
+}
+
+func sm2ResultField(result interface{}, field string) string {
+	var fields map[string]interface{}
+	bytez, _ := json.Marshal(result)
+	json.Unmarshal(bytez, &fields)
+	value, _ := fields[field].(string)
+	return value
+}
+
+func TestSM2GetAddress(t *testing.T) {
+	result, err := SM2Content(&AsymmetricBody{Operation: "get_address", Content: sm2TestKey})
+	assert.Nil(t, err)
+	assert.Equal(t, sm2TestPubkey, sm2ResultField(result, "pubkey"))
+	assert.Equal(t, "4a21554fcca7fdd8c183ecaab3a797c7dfce6de5", sm2ResultField(result, "address"))
+}
+
+func TestSM2SignVerify(t *testing.T) {
+	verify := func(signature, format, uid, message string) bool {
+		result, err := SM2Content(&AsymmetricBody{Operation: "verify", Pubkey: sm2TestPubkey, Signature: signature,
+			SignatureFormat: format, Uid: uid, Message: message})
+		assert.Nil(t, err)
+		return result.(struct {
+			Valid bool `json:"valid"`
+		}).Valid
+	}
+	for _, uid := range []string{"", "ALICE123@YAHOO.COM"} {
+		signed, err := SM2Content(&AsymmetricBody{Operation: "sign", Content: sm2TestKey, Uid: uid, Message: "message digest"})
+		if !assert.Nil(t, err) {
+			continue
+		}
+		raw, der := sm2ResultField(signed, "signature"), sm2ResultField(signed, "der")
+		assert.Equal(t, 128, len(raw))
+		// raw is r||s, the der SEQUENCE holds the same integers
+		r, s, err := parseSM2Signature(mustDecodeHex(der), "der")
+		assert.Nil(t, err)
+		assert.Equal(t, raw, hex.EncodeToString(append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)))
+
+		for _, format := range []string{"", "raw"} {
+			assert.True(t, verify(raw, format, uid, "message digest"), "raw %q %q", format, uid)
+		}
+		for _, format := range []string{"", "der"} {
+			assert.True(t, verify(der, format, uid, "message digest"), "der %q %q", format, uid)
+		}
+		assert.False(t, verify(raw, "", uid, "message digesT"))
+	}
+
+	// a custom uid changes Z, so the signature fails under the default one and vice versa
+	signed, err := SM2Content(&AsymmetricBody{Operation: "sign", Content: sm2TestKey, Uid: "ALICE123@YAHOO.COM", Message: "message digest"})
+	assert.Nil(t, err)
+	assert.False(t, verify(sm2ResultField(signed, "signature"), "", "", "message digest"))
+	assert.False(t, verify(sm2ResultField(signed, "der"), "", "1234567812345679", "message digest"))
+	signed, err = SM2Content(&AsymmetricBody{Operation: "sign", Content: sm2TestKey, Message: "message digest"})
+	assert.Nil(t, err)
+	assert.True(t, verify(sm2ResultField(signed, "signature"), "", "1234567812345678", "message digest"))
+	assert.False(t, verify(sm2ResultField(signed, "signature"), "", "ALICE123@YAHOO.COM", "message digest"))
+
+	_, err = SM2Content(&AsymmetricBody{Operation: "verify", Pubkey: sm2TestPubkey, Signature: "00", SignatureFormat: "raw", Message: "m"})
+	assert.EqualError(t, err, "invalid raw signature length: 1")
+	_, err = SM2Content(&AsymmetricBody{Operation: "verify", Pubkey: sm2TestPubkey, Signature: "3000", Message: "m"})
+	assert.ErrorContains(t, err, "invalid der signature")
+}
+
+func mustDecodeHex(s string) []byte {
+	bytez, _ := hex.DecodeString(s)
+	return bytez
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/tjfoc/gmsm/sm2"
+)
+
+const (
+	// C1 is 04||x||y, C3 the SM3 hash and C2 the xored plaintext
+	sm2C1Size = 65
+	sm2C3Size = 32
+)
+
+// sm2ASN1Cipher is the GM/T 0009 SEQUENCE { x INTEGER, y INTEGER, hash OCTET STRING, ciphertext OCTET STRING }
+type sm2ASN1Cipher struct {
+	X          *big.Int
+	Y          *big.Int
+	Hash       []byte
+	CipherText []byte
+}
+
+// ParseSM2Ciphertext turns a c1c3c2 (default), c1c2c3 or asn1 ciphertext into c1c3c2
+func ParseSM2Ciphertext(data []byte, layout string) ([]byte, error) {
+	var c1, c2, c3 []byte
+	switch layout {
+	case "", "c1c3c2", "c1c2c3":
+		if len(data) <= sm2C1Size+sm2C3Size || data[0] != 0x04 {
+			return nil, errors.New("invalid sm2 ciphertext: want 04||x||y followed by at least 33 bytes")
+		}
+		c1 = data[:sm2C1Size]
+		if layout == "c1c2c3" {
+			c2, c3 = data[sm2C1Size:len(data)-sm2C3Size], data[len(data)-sm2C3Size:]
+		} else {
+			c3, c2 = data[sm2C1Size:sm2C1Size+sm2C3Size], data[sm2C1Size+sm2C3Size:]
+		}
+	case "asn1":
+		var cipher sm2ASN1Cipher
+		rest, err := asn1.Unmarshal(data, &cipher)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sm2 asn1 ciphertext: %v", err)
+		}
+		if len(rest) > 0 {
+			return nil, fmt.Errorf("invalid sm2 asn1 ciphertext: %d trailing bytes", len(rest))
+		}
+		if cipher.X.Sign() < 0 || cipher.X.BitLen() > 256 || cipher.Y.Sign() < 0 || cipher.Y.BitLen() > 256 {
+			return nil, errors.New("invalid sm2 asn1 ciphertext: coordinate out of range")
+		}
+		if len(cipher.Hash) != sm2C3Size || len(cipher.CipherText) == 0 {
+			return nil, errors.New("invalid sm2 asn1 ciphertext: want a 32-byte hash and a non-empty ciphertext")
+		}
+		c1 = make([]byte, sm2C1Size)
+		c1[0] = 0x04
+		cipher.X.FillBytes(c1[1:33])
+		cipher.Y.FillBytes(c1[33:])
+		c2, c3 = cipher.CipherText, cipher.Hash
+	default:
+		return nil, fmt.Errorf("invalid sm2 ciphertext layout: %s, want c1c3c2, c1c2c3 or asn1", layout)
+	}
+	x, y := new(big.Int).SetBytes(c1[1:33]), new(big.Int).SetBytes(c1[33:])
+	if !sm2.P256Sm2().IsOnCurve(x, y) {
+		return nil, errors.New("invalid sm2 ciphertext: C1 is not on curve")
+	}
+	return append(append(append([]byte{}, c1...), c3...), c2...), nil
+}
+
+// FormatSM2Ciphertext writes a c1c3c2 ciphertext in layout
+func FormatSM2Ciphertext(c1c3c2 []byte, layout string) ([]byte, error) {
+	c1 := c1c3c2[:sm2C1Size]
+	c3 := c1c3c2[sm2C1Size : sm2C1Size+sm2C3Size]
+	c2 := c1c3c2[sm2C1Size+sm2C3Size:]
+	switch layout {
+	case "", "c1c3c2":
+		return c1c3c2, nil
+	case "c1c2c3":
+		return append(append(append([]byte{}, c1...), c2...), c3...), nil
+	case "asn1":
+		return asn1.Marshal(sm2ASN1Cipher{
+			X:          new(big.Int).SetBytes(c1[1:33]),
+			Y:          new(big.Int).SetBytes(c1[33:]),
+			Hash:       c3,
+			CipherText: c2,
+		})
+	}
+	return nil, fmt.Errorf("invalid sm2 ciphertext layout: %s, want c1c3c2, c1c2c3 or asn1", layout)
+}
+
+func ConvertSM2Ciphertext(data []byte, from, to string) ([]byte, error) {
+	c1c3c2, err := ParseSM2Ciphertext(data, from)
+	if err != nil {
+		return nil, err
+	}
+	return FormatSM2Ciphertext(c1c3c2, to)
+}
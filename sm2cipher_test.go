@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tjfoc/gmsm/sm2"
+)
+
+const (
+	sm2TestKey    = "ae08dc67186f140235a36a06e55dc2ccabbc5365525825c382aa36e055de84cd"
+	sm2TestPubkey = "046fd993301a0b380677fc89d6e8ad0ea4b5068e4764051c407f1a451e77d889d1298ed2d9c4ccfa56df693e3672839fe894ee7b5ef20836c18929c090c1e3e661"
+)
+
+// "encryption standard" encrypted by emmansun/gmsm with the ephemeral key read from 64 bytes of 0x5a,
+// x of C1 has the high bit set so its DER INTEGER takes a leading zero
+var sm2TestCiphertexts = map[string]string{
+	"c1c3c2": "049559313088e69922f1aee1319b7da1b4951073d2625bd7c272ec91b09a76e5ce0e204392f0df313059062cbb8077f5a215e1d12f15ff6c672dcc8fb6253ecf44" +
+		"e51a63b3bcb92ee35d19964e26a4e07c6586aeed61187b8247a8b003bcdcd4db" + "718ac99d39745b0c68b14d58b3c15e4656a713",
+	"c1c2c3": "049559313088e69922f1aee1319b7da1b4951073d2625bd7c272ec91b09a76e5ce0e204392f0df313059062cbb8077f5a215e1d12f15ff6c672dcc8fb6253ecf44" +
+		"718ac99d39745b0c68b14d58b3c15e4656a713" + "e51a63b3bcb92ee35d19964e26a4e07c6586aeed61187b8247a8b003bcdcd4db",
+	"asn1": "307c" + "0221009559313088e69922f1aee1319b7da1b4951073d2625bd7c272ec91b09a76e5ce" +
+		"02200e204392f0df313059062cbb8077f5a215e1d12f15ff6c672dcc8fb6253ecf44" +
+		"0420e51a63b3bcb92ee35d19964e26a4e07c6586aeed61187b8247a8b003bcdcd4db" + "0413718ac99d39745b0c68b14d58b3c15e4656a713",
+}
+
+func TestSM2CiphertextVector(t *testing.T) {
+	for layout, ciphertext := range sm2TestCiphertexts {
+		msg, err := SM2Content(&AsymmetricBody{Operation: "decrypt", Content: sm2TestKey, Ciphertext: ciphertext, Layout: layout})
+		assert.Nil(t, err, layout)
+		assert.Equal(t, "encryption standard", msg, layout)
+		for to, expected := range sm2TestCiphertexts {
+			converted, err := SM2Content(&AsymmetricBody{Operation: "convert", Ciphertext: ciphertext, Layout: layout, ToLayout: to})
+			assert.Nil(t, err)
+			assert.Equal(t, expected, converted, "%s to %s", layout, to)
+		}
+	}
+	// the layout is never guessed, c1c3c2 bytes read as c1c2c3 fail the hash check
+	_, err := SM2Content(&AsymmetricBody{Operation: "decrypt", Content: sm2TestKey, Ciphertext: sm2TestCiphertexts["c1c3c2"], Layout: "c1c2c3"})
+	assert.NotNil(t, err)
+}
+
+func TestSM2EncryptRoundTrip(t *testing.T) {
+	for _, layout := range []string{"", "c1c3c2", "c1c2c3", "asn1"} {
+		for _, msg := range []string{"a", "encryption standard", strings.Repeat("0123456789", 10)} {
+			ciphertext, err := SM2Content(&AsymmetricBody{Operation: "encrypt", Pubkey: "0x" + sm2TestPubkey, Message: msg, Layout: layout})
+			if !assert.Nil(t, err, layout) {
+				continue
+			}
+			raw, _ := hex.DecodeString(ciphertext.(string))
+			if layout == "asn1" {
+				assert.Equal(t, byte(0x30), raw[0])
+			} else {
+				assert.Equal(t, sm2C1Size+sm2C3Size+len(msg), len(raw))
+				assert.Equal(t, byte(0x04), raw[0])
+			}
+			decrypted, err := SM2Content(&AsymmetricBody{Operation: "decrypt", Content: sm2TestKey, Ciphertext: ciphertext.(string), Layout: layout})
+			assert.Nil(t, err, layout)
+			assert.Equal(t, msg, decrypted, layout)
+		}
+	}
+	_, err := SM2Content(&AsymmetricBody{Operation: "encrypt", Pubkey: sm2TestPubkey, Layout: "c2c1c3", Message: "a"})
+	assert.NotNil(t, err)
+}
+
+// a C1 whose x is below 2^248 has a DER INTEGER of 31 bytes or less, which must come back zero padded
+func TestSM2CiphertextShortCoordinate(t *testing.T) {
+	curve := sm2.P256Sm2()
+	var x, y *big.Int
+	for k := int64(1); x == nil || x.BitLen() > 248; k++ {
+		x, y = curve.ScalarBaseMult(big.NewInt(k).Bytes())
+	}
+	der, err := asn1.Marshal(sm2ASN1Cipher{X: x, Y: y, Hash: bytes.Repeat([]byte{1}, sm2C3Size), CipherText: []byte{2}})
+	assert.Nil(t, err)
+	c1c3c2, err := ParseSM2Ciphertext(der, "asn1")
+	assert.Nil(t, err)
+	assert.Equal(t, sm2C1Size+sm2C3Size+1, len(c1c3c2))
+	assert.Equal(t, byte(0x04), c1c3c2[0])
+	assert.Equal(t, byte(0), c1c3c2[1])
+	assert.Equal(t, x, new(big.Int).SetBytes(c1c3c2[1:33]))
+	assert.Equal(t, y, new(big.Int).SetBytes(c1c3c2[33:65]))
+
+	back, err := FormatSM2Ciphertext(c1c3c2, "asn1")
+	assert.Nil(t, err)
+	assert.Equal(t, der, back)
+}
+
+func TestSM2CiphertextMalformed(t *testing.T) {
+	plain, _ := hex.DecodeString(sm2TestCiphertexts["c1c3c2"])
+	der, _ := hex.DecodeString(sm2TestCiphertexts["asn1"])
+	compressed := append([]byte{0x02}, plain[1:]...)
+	offCurve := append([]byte{}, plain...)
+	offCurve[64] ^= 1
+	var shortHash, emptyText, negative []byte
+	var cipher sm2ASN1Cipher
+	asn1.Unmarshal(der, &cipher)
+	c := cipher
+	c.Hash = c.Hash[:31]
+	shortHash, _ = asn1.Marshal(c)
+	c = cipher
+	c.CipherText = []byte{}
+	emptyText, _ = asn1.Marshal(c)
+	c = cipher
+	c.X = new(big.Int).Neg(c.X)
+	negative, _ = asn1.Marshal(c)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		layout string
+	}{
+		{"empty", nil, ""},
+		{"C1 only", plain[:sm2C1Size], "c1c3c2"},
+		{"no C2", plain[:sm2C1Size+sm2C3Size], "c1c3c2"},
+		{"no C2", plain[:sm2C1Size+sm2C3Size], "c1c2c3"},
+		{"compressed C1", compressed, "c1c3c2"},
+		{"C1 off curve", offCurve, "c1c2c3"},
+		{"truncated der", der[:len(der)-1], "asn1"},
+		{"trailing der", append(append([]byte{}, der...), 0), "asn1"},
+		{"plain as der", plain, "asn1"},
+		{"31-byte hash", shortHash, "asn1"},
+		{"empty C2", emptyText, "asn1"},
+		{"negative x", negative, "asn1"},
+		{"unknown layout", plain, "c2c3c1"},
+	}
+	for _, test := range tests {
+		_, err := ParseSM2Ciphertext(test.data, test.layout)
+		assert.NotNil(t, err, test.name)
+		_, err = SM2Content(&AsymmetricBody{Operation: "decrypt", Content: sm2TestKey, Ciphertext: hex.EncodeToString(test.data), Layout: test.layout})
+		assert.NotNil(t, err, test.name)
+	}
+
+	// a flipped C2 or C3 byte parses but fails the C3 hash check
+	for _, i := range []int{sm2C1Size, len(plain) - 1} {
+		tampered := append([]byte{}, plain...)
+		tampered[i] ^= 1
+		_, err := SM2Content(&AsymmetricBody{Operation: "decrypt", Content: sm2TestKey, Ciphertext: hex.EncodeToString(tampered)})
+		assert.EqualError(t, err, "sm2 decryption failed: wrong key, layout or corrupted ciphertext")
+	}
+}
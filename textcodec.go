@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+)
+
+const (
+	zbase32Alphabet = "ybndrfg8ejkmcpqxot1uwisza345h769"
+	mimeLineLength  = 76
+)
+
+var zbase32Encoding = base32.NewEncoding(zbase32Alphabet).WithPadding(base32.NoPadding)
+
+// TextCodec turns bytes into text and back, exposed as <name>_encode and <name>_decode
+type TextCodec struct {
+	Encode func([]byte) string
+	Decode func(string) ([]byte, error)
+	Padded bool
+}
+
+var textCodecs = map[string]*TextCodec{
+	//base64 keeps the URL alphabet it has always used
+	"base64":       {base64.URLEncoding.EncodeToString, base64.URLEncoding.DecodeString, true},
+	"base64std":    {base64.StdEncoding.EncodeToString, base64.StdEncoding.DecodeString, true},
+	"base64url":    {base64.URLEncoding.EncodeToString, base64.URLEncoding.DecodeString, true},
+	"base64raw":    {base64.RawStdEncoding.EncodeToString, base64.RawStdEncoding.DecodeString, false},
+	"base64rawurl": {base64.RawURLEncoding.EncodeToString, base64.RawURLEncoding.DecodeString, false},
+	"base64mime":   {encodeBase64MIME, decodeBase64MIME, true},
+	"base32":       {base32.StdEncoding.EncodeToString, base32.StdEncoding.DecodeString, true},
+	"base32hex":    {base32.HexEncoding.EncodeToString, base32.HexEncoding.DecodeString, true},
+	"zbase32":      {zbase32Encoding.EncodeToString, zbase32Encoding.DecodeString, false},
+	"hex":          {hex.EncodeToString, hex.DecodeString, false},
+	"base58":       {base58.Encode, DecodeBase58, false},
+}
+
+// RFC 2045 base64, standard alphabet wrapped at 76 characters with CRLF
+func encodeBase64MIME(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var lines []string
+	for len(encoded) > mimeLineLength {
+		lines = append(lines, encoded[:mimeLineLength])
+		encoded = encoded[mimeLineLength:]
+	}
+	return strings.Join(append(lines, encoded), "\r\n")
+}
+
+func decodeBase64MIME(content string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+}
+
+// candidates of auto_decode, most specific alphabet first
+var autoDecodeOrder = []string{"hex", "base32", "base32hex", "zbase32", "base64std", "base64url", "base64raw", "base64rawurl", "base58"}
+
+type AutoDecodeMatch struct {
+	Encoding string `json:"encoding"`
+	Padded   bool   `json:"padded"`
+	Data     string `json:"data"`
+}
+
+type AutoDecodeResult struct {
+	//the most specific alphabet that decodes content
+	AutoDecodeMatch
+	//every alphabet that decodes content, most specific first
+	Matches []AutoDecodeMatch `json:"matches"`
+	//set when the matches decode to different bytes
+	Ambiguous bool `json:"ambiguous"`
+}
+
+// AutoDecode tries every text codec, e.g. deadbeef is both hex and base64 and flagged ambiguous
+func AutoDecode(content, outputEncoding string) (*AutoDecodeResult, error) {
+	trimmed := strings.TrimSpace(content)
+	order := autoDecodeOrder
+	if strings.ContainsAny(trimmed, "\r\n") {
+		order = []string{"base64mime"}
+	}
+	result := &AutoDecodeResult{}
+	var first []byte
+	for _, name := range order {
+		codec := textCodecs[name]
+		decoded, err := codec.Decode(trimmed)
+		if err != nil || (len(decoded) == 0 && trimmed != "") {
+			continue
+		}
+		match := AutoDecodeMatch{Encoding: name, Padded: codec.Padded && strings.HasSuffix(trimmed, "=")}
+		match.Data, err = EncodeContent(decoded, outputEncoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Matches) == 0 {
+			first = decoded
+			result.AutoDecodeMatch = match
+		} else if !bytes.Equal(first, decoded) {
+			result.Ambiguous = true
+		}
+		result.Matches = append(result.Matches, match)
+	}
+	if len(result.Matches) == 0 {
+		return nil, fmt.Errorf("content matches none of %s", strings.Join(order, ", "))
+	}
+	return result, nil
+}
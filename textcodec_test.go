@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func codecRoundTrip(t *testing.T, name, data, encoded string) {
+	result, err := CodecContent(&CodecBody{Method: name + "_encode", Content: data})
+	assert.Nil(t, err)
+	assert.Equal(t, encoded, result, "%s_encode %q", name, data)
+	result, err = CodecContent(&CodecBody{Method: name + "_decode", Content: encoded})
+	assert.Nil(t, err)
+	assert.Equal(t, data, result, "%s_decode %q", name, encoded)
+}
+
+// RFC 4648 section 10
+func TestTextCodecRFC4648(t *testing.T) {
+	vectors := map[string][]string{
+		"base64std": {"", "Zg==", "Zm8=", "Zm9v", "Zm9vYg==", "Zm9vYmE=", "Zm9vYmFy"},
+		"base64url": {"", "Zg==", "Zm8=", "Zm9v", "Zm9vYg==", "Zm9vYmE=", "Zm9vYmFy"},
+		"base64raw": {"", "Zg", "Zm8", "Zm9v", "Zm9vYg", "Zm9vYmE", "Zm9vYmFy"},
+		"base32":    {"", "MY======", "MZXQ====", "MZXW6===", "MZXW6YQ=", "MZXW6YTB", "MZXW6YTBOI======"},
+		"base32hex": {"", "CO======", "CPNG====", "CPNMU===", "CPNMUOG=", "CPNMUOJ1", "CPNMUOJ1E8======"},
+		"hex":       {"", "66", "666f", "666f6f", "666f6f62", "666f6f6261", "666f6f626172"},
+	}
+	for name, encoded := range vectors {
+		for i, e := range encoded {
+			codecRoundTrip(t, name, "foobar"[:i], e)
+		}
+	}
+}
+
+func TestTextCodecVariants(t *testing.T) {
+	// 0xfb 0xff uses both characters the base64 alphabets disagree on
+	tests := map[string]string{
+		"base64":       "-_8=",
+		"base64std":    "+/8=",
+		"base64url":    "-_8=",
+		"base64raw":    "+/8",
+		"base64rawurl": "-_8",
+		"base64mime":   "+/8=",
+	}
+	for name, encoded := range tests {
+		result, err := CodecContent(&CodecBody{Method: name + "_encode", Content: "fbff", ContentEncoding: ContentEncoding{InputEncoding: EncodingHex}})
+		assert.Nil(t, err)
+		assert.Equal(t, encoded, result, name)
+		result, err = CodecContent(&CodecBody{Method: name + "_decode", Content: encoded, ContentEncoding: ContentEncoding{OutputEncoding: EncodingHex}})
+		assert.Nil(t, err)
+		assert.Equal(t, "fbff", result, name)
+	}
+	for _, name := range []string{"base64std", "base64raw"} {
+		_, err := CodecContent(&CodecBody{Method: name + "_decode", Content: "-_8"})
+		assert.NotNil(t, err, name)
+	}
+
+	// RFC 2045 lines are at most 76 characters, joined by CRLF
+	data := strings.Repeat("a", 100)
+	encoded := encodeBase64MIME([]byte(data))
+	lines := strings.Split(encoded, "\r\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, mimeLineLength, len(lines[0]))
+	codecRoundTrip(t, "base64mime", data, encoded)
+}
+
+// z-base-32 vectors of the specification by Zooko Wilcox-O'Hearn
+func TestZBase32(t *testing.T) {
+	tests := map[string]string{
+		"f0bfc7": "6n9hq",
+		"d47a04": "4t7ye",
+		"":       "",
+	}
+	for data, encoded := range tests {
+		result, err := CodecContent(&CodecBody{Method: "zbase32_encode", Content: data, ContentEncoding: ContentEncoding{InputEncoding: EncodingHex}})
+		assert.Nil(t, err)
+		assert.Equal(t, encoded, result, data)
+		result, err = CodecContent(&CodecBody{Method: "zbase32_decode", Content: encoded, ContentEncoding: ContentEncoding{OutputEncoding: EncodingHex}})
+		assert.Nil(t, err)
+		assert.Equal(t, data, result, encoded)
+	}
+}
+
+func TestAutoDecode(t *testing.T) {
+	// deadbeef is hex, z-base-32 and base64 at once
+	result, err := AutoDecode("deadbeef", EncodingHex)
+	assert.Nil(t, err)
+	assert.Equal(t, "hex", result.Encoding)
+	assert.Equal(t, "deadbeef", result.Data)
+	assert.True(t, result.Ambiguous)
+	var encodings []string
+	for _, match := range result.Matches {
+		encodings = append(encodings, match.Encoding)
+		if match.Encoding == "base64std" {
+			assert.Equal(t, "75e69d6de79f", match.Data)
+		}
+	}
+	assert.Contains(t, encodings, "hex")
+	assert.Contains(t, encodings, "base64std")
+	assert.Contains(t, encodings, "base64url")
+
+	// the padded URL alphabet only decodes one way
+	result, err = AutoDecode("-_8=", EncodingHex)
+	assert.Nil(t, err)
+	assert.Equal(t, "base64url", result.Encoding)
+	assert.True(t, result.Padded)
+	assert.False(t, result.Ambiguous)
+	assert.Equal(t, 1, len(result.Matches))
+
+	// base64std and base64url agree on alphabets without + / - _
+	result, err = AutoDecode("Zm9vYmFy", EncodingUTF8)
+	assert.Nil(t, err)
+	assert.Equal(t, "foobar", result.Data)
+	for _, match := range result.Matches {
+		if strings.HasPrefix(match.Encoding, "base64") {
+			assert.Equal(t, "foobar", match.Data)
+		}
+	}
+
+	result, err = AutoDecode("Zm9v\r\nYmFy", EncodingUTF8)
+	assert.Nil(t, err)
+	assert.Equal(t, "base64mime", result.Encoding)
+	assert.Equal(t, "foobar", result.Data)
+
+	_, err = AutoDecode("!!!", EncodingUTF8)
+	assert.NotNil(t, err)
+}